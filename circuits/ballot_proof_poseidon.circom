@@ -53,7 +53,7 @@ template BallotProof(n_fields) {
     var static_inputs = 14; // excluding k and secret
     var cipherfields_inputs = 4 * n_fields;
     var n_inputs = cipherfields_inputs + static_inputs; 
-    component inputs_hasher = MultiPoseidon(n_inputs);
+    component inputs_hasher = MultiPoseidonTree(n_inputs);
     var i = 0;
     inputs_hasher.in[i] <== process_id; i++;        // Process.ID
     inputs_hasher.in[i] <== max_count; i++;         // Process.BallotMode
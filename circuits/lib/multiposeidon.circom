@@ -45,4 +45,54 @@ template MultiPoseidon(n_inputs) {
         final_hash.inputs[i] <== intermediate_hashes[i].out;
     }
     out <== final_hash.out;
-}
\ No newline at end of file
+}
+
+// MultiPoseidonTree is a circuit to hash any number of inputs using a tree of
+// Poseidon hashes. It splits the inputs in groups of 16 and hashes them using
+// the Poseidon hash, then it splits and hashes the resulting hashes in the
+// same way until a single hash remains, which is the root of the tree. The
+// result is the same as the MultiPoseidon one for more than 16 and up to 256
+// inputs.
+template MultiPoseidonTree(n_inputs) {
+    assert(n_inputs > 0);
+    // inputs and output
+    signal input in[n_inputs];
+    signal output out;
+    // calculate the required number of hashes of every level of the tree
+    var n_hashes = (n_inputs + 15) \ 16;
+    var level_hashes = n_hashes;
+    while (level_hashes > 1) {
+        level_hashes = (level_hashes + 15) \ 16;
+        n_hashes += level_hashes;
+    }
+    // the values of the tree are the inputs followed by the hashes of every
+    // level, so the values of a level are the hashes of the previous one
+    signal values[n_inputs + n_hashes];
+    for (var i = 0; i < n_inputs; i++) {
+        values[i] <== in[i];
+    }
+    // hash every chunk of every level
+    component hashes[n_hashes];
+    var offset = 0;
+    var level_size = n_inputs;
+    var h = 0;
+    while (h < n_hashes) {
+        var n_chunks = (level_size + 15) \ 16;
+        for (var i = 0; i < n_chunks; i++) {
+            var chunk_size = 16;
+            if (i == n_chunks - 1) {
+                chunk_size = level_size - 16 * i;
+            }
+            hashes[h] = Poseidon(chunk_size);
+            for (var j = 0; j < chunk_size; j++) {
+                hashes[h].inputs[j] <== values[offset + 16 * i + j];
+            }
+            values[n_inputs + h] <== hashes[h].out;
+            h++;
+        }
+        offset += level_size;
+        level_size = n_chunks;
+    }
+    // the root of the tree is the last hash
+    out <== values[n_inputs + n_hashes - 1];
+}
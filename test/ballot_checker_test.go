@@ -3,6 +3,7 @@ package test
 import (
 	"encoding/json"
 	"log"
+	"math/big"
 	"os"
 	"strconv"
 	"testing"
@@ -35,21 +36,48 @@ func ballotToStrings(vals []int64) []string {
 	return out
 }
 
-func TestBallotChecker(t *testing.T) {
-	type tc struct {
-		name         string
-		fields       []int64 // raw field values (<= 8 non‑zero entries)
-		maxCount     int     // logical field count provided by the ballot
-		forceUnique  bool    // uniqueness flag
-		maxValue     int
-		minValue     int
-		maxTotalCost int
-		minTotalCost int
-		costExp      int
-		expectPass   bool
+type ballotCheckerCase struct {
+	name         string
+	fields       []int64 // raw field values (<= 8 non‑zero entries)
+	maxCount     int     // logical field count provided by the ballot
+	forceUnique  bool    // uniqueness flag
+	maxValue     int
+	minValue     int
+	maxTotalCost int
+	minTotalCost int
+	costExp      int
+	// costFromWeight uses weight as the max total cost
+	costFromWeight bool
+	weight         *big.Int
+	expectPass     bool
+}
+
+// ballotMode returns the ballot mode of the test case.
+func (tc ballotCheckerCase) ballotMode() *utils.BallotMode {
+	return &utils.BallotMode{
+		MaxCount:        uint64(tc.maxCount),
+		ForceUniqueness: tc.forceUnique,
+		MaxValue:        uint64(tc.maxValue),
+		MinValue:        uint64(tc.minValue),
+		MaxTotalCost:    big.NewInt(int64(tc.maxTotalCost)),
+		MinTotalCost:    big.NewInt(int64(tc.minTotalCost)),
+		CostExp:         uint64(tc.costExp),
+		CostFromWeight:  tc.costFromWeight,
+	}
+}
+
+// voterWeight returns the weight of the test case, zero if it is not set.
+func (tc ballotCheckerCase) voterWeight() *big.Int {
+	if tc.weight == nil {
+		return big.NewInt(0)
 	}
+	return tc.weight
+}
 
-	cases := []tc{
+// ballotCheckerCases returns the cases shared by the circuit and the native
+// implementation of the ballot checker.
+func ballotCheckerCases() []ballotCheckerCase {
+	return []ballotCheckerCase{
 		{
 			name:         "Simple 5‑star rating – valid",
 			fields:       []int64{3, 2, 5},
@@ -218,9 +246,46 @@ func TestBallotChecker(t *testing.T) {
 			costExp:      1,
 			expectPass:   false,
 		},
+		{
+			name:           "Weight of 128 bits as max total cost – valid",
+			fields:         []int64{3, 2, 1},
+			maxCount:       3,
+			maxValue:       5,
+			maxTotalCost:   1,
+			costExp:        2,
+			costFromWeight: true,
+			weight:         new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1)),
+			expectPass:     true,
+		},
+		{
+			// LessEqThan(128) fits while weight - total does not underflow
+			// 129 bits
+			name:           "Weight of 129 bits as max total cost – valid",
+			fields:         []int64{3, 2, 1}, // cost = 9+4+1 = 14
+			maxCount:       3,
+			maxValue:       5,
+			maxTotalCost:   1,
+			costExp:        2,
+			costFromWeight: true,
+			weight:         new(big.Int).Lsh(big.NewInt(1), 128),
+			expectPass:     true,
+		},
+		{
+			name:           "Weight out of the comparator range – invalid",
+			fields:         []int64{3, 2, 1}, // cost = 9+4+1 = 14
+			maxCount:       3,
+			maxValue:       5,
+			maxTotalCost:   1,
+			costExp:        2,
+			costFromWeight: true,
+			weight:         new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(14)),
+			expectPass:     false,
+		},
 	}
+}
 
-	for _, tc := range cases {
+func TestBallotChecker(t *testing.T) {
+	for _, tc := range ballotCheckerCases() {
 		t.Run(tc.name, func(t *testing.T) {
 			c := qt.New(t)
 
@@ -232,6 +297,10 @@ func TestBallotChecker(t *testing.T) {
 			if tc.forceUnique {
 				uniq = "1"
 			}
+			fromWeight := "0"
+			if tc.costFromWeight {
+				fromWeight = "1"
+			}
 
 			inputs := map[string]any{
 				"fields":           ballotToStrings(padded),
@@ -242,8 +311,8 @@ func TestBallotChecker(t *testing.T) {
 				"max_total_cost":   strconv.Itoa(tc.maxTotalCost),
 				"min_total_cost":   strconv.Itoa(tc.minTotalCost),
 				"cost_exp":         strconv.Itoa(tc.costExp),
-				"weight":           "0",
-				"cost_from_weight": "0",
+				"weight":           tc.voterWeight().String(),
+				"cost_from_weight": fromWeight,
 			}
 
 			bInputs, err := json.MarshalIndent(inputs, "  ", "  ")
@@ -274,3 +343,102 @@ func TestBallotChecker(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckBallot(t *testing.T) {
+	for _, tc := range ballotCheckerCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			fields := []*big.Int{}
+			for _, v := range padToEight(tc.fields) {
+				fields = append(fields, big.NewInt(v))
+			}
+			err := utils.CheckBallot(fields, tc.ballotMode(), tc.voterWeight())
+			if tc.expectPass {
+				c.Assert(err, qt.IsNil)
+			} else {
+				c.Assert(err, qt.ErrorAs, new(*utils.BallotError))
+			}
+		})
+	}
+}
+
+func TestCheckBallotErrors(t *testing.T) {
+	c := qt.New(t)
+
+	mode := &utils.BallotMode{
+		MaxCount:        3,
+		ForceUniqueness: true,
+		MaxValue:        15,
+		MinValue:        1,
+		MaxTotalCost:    big.NewInt(225),
+		MinTotalCost:    big.NewInt(3),
+		CostExp:         2,
+	}
+	toBigInts := func(vals ...int64) []*big.Int {
+		fields := []*big.Int{}
+		for _, v := range padToEight(vals) {
+			fields = append(fields, big.NewInt(v))
+		}
+		return fields
+	}
+	cases := []struct {
+		fields []int64
+		weight int64
+		mode   *utils.BallotMode
+		rule   utils.BallotRule
+		msg    string
+	}{
+		{[]int64{2, 1, 2}, 0, mode, utils.RuleUniqueness, "field 2 duplicates field 0"},
+		{[]int64{2, 16, 3}, 0, mode, utils.RuleMaxValue, "field 1 value 16 exceeds max value 15"},
+		{[]int64{2, 0, 3}, 0, mode, utils.RuleMinValue, "field 1 value 0 is lower than min value 1"},
+		{[]int64{14, 1, 15}, 0, mode, utils.RuleMaxTotalCost, "total cost 422 exceeds 225"},
+		{[]int64{1, 1, 1}, 0, &utils.BallotMode{MaxCount: 3, MaxValue: 15, MinTotalCost: big.NewInt(4), CostExp: 2}, utils.RuleMinTotalCost, "total cost 3 is lower than 4"},
+		{[]int64{5, 5, 5}, 50, &utils.BallotMode{MaxCount: 3, MaxValue: 15, MaxTotalCost: big.NewInt(1), CostExp: 2, CostFromWeight: true}, utils.RuleMaxTotalCost, "total cost 75 exceeds 50"},
+		{[]int64{1}, 0, &utils.BallotMode{MaxCount: 9, MaxValue: 15, CostExp: 1}, utils.RuleMaxCount, "max count 9 exceeds the number of fields 8"},
+	}
+	for _, tc := range cases {
+		err := utils.CheckBallot(toBigInts(tc.fields...), tc.mode, big.NewInt(tc.weight))
+		ballotErr := &utils.BallotError{}
+		c.Assert(err, qt.ErrorAs, &ballotErr)
+		c.Assert(ballotErr.Rule, qt.Equals, tc.rule)
+		c.Assert(err.Error(), qt.Equals, tc.msg)
+	}
+	// duplicated and out of bounds values are allowed in the padding
+	c.Assert(utils.CheckBallot(toBigInts(2, 1, 3, 3, 99), mode, nil), qt.IsNil)
+	// the weight is used as max total cost only if max total cost is not zero
+	fromWeight := &utils.BallotMode{MaxCount: 3, MaxValue: 15, CostExp: 2, CostFromWeight: true}
+	c.Assert(utils.CheckBallot(toBigInts(5, 5, 5), fromWeight, big.NewInt(50)), qt.IsNil)
+	// the comparators must be satisfiable even if max total cost is zero
+	twoTo128 := new(big.Int).Lsh(big.NewInt(1), 128)
+	twoTo129 := new(big.Int).Lsh(big.NewInt(1), 129)
+	c.Assert(utils.CheckBallot(toBigInts(5, 5, 5), fromWeight, twoTo128), qt.IsNil)
+	sizeCases := []struct {
+		mode   *utils.BallotMode
+		weight *big.Int
+		rule   utils.BallotRule
+		msg    string
+	}{
+		{
+			fromWeight, new(big.Int).Add(twoTo128, big.NewInt(75)), utils.RuleWeightSize,
+			"weight 340282366920938463463374607431768211531 out of the comparator range of total cost 75",
+		},
+		{
+			&utils.BallotMode{MaxCount: 3, MaxValue: 15, MaxTotalCost: twoTo129, CostExp: 2}, nil,
+			utils.RuleMaxTotalCostSize,
+			"max total cost 680564733841876926926749214863536422912 out of the 128 bits comparator range",
+		},
+		{
+			&utils.BallotMode{MaxCount: 3, MaxValue: 15, MinTotalCost: twoTo129, CostExp: 2}, nil,
+			utils.RuleMinTotalCostSize,
+			"min total cost 680564733841876926926749214863536422912 out of the 128 bits comparator range",
+		},
+	}
+	for _, tc := range sizeCases {
+		err := utils.CheckBallot(toBigInts(5, 5, 5), tc.mode, tc.weight)
+		ballotErr := &utils.BallotError{}
+		c.Assert(err, qt.ErrorAs, &ballotErr)
+		c.Assert(ballotErr.Rule, qt.Equals, tc.rule)
+		c.Assert(err.Error(), qt.Equals, tc.msg)
+	}
+}
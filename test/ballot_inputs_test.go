@@ -0,0 +1,156 @@
+package test
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	"github.com/iden3/go-iden3-crypto/constants"
+	"github.com/iden3/go-iden3-crypto/mimc7"
+	"github.com/iden3/go-rapidsnark/witness/v2"
+	"github.com/vocdoni/z-ircuits/utils"
+	"go.vocdoni.io/dvote/util"
+)
+
+// testProcessAndVoter returns a random process and voter for the ballot mode
+// provided.
+func testProcessAndVoter(c *qt.C, mode *utils.BallotMode, weight *big.Int) (*utils.Process, *utils.Voter) {
+	privKey, pubKey := utils.GenerateKeyPair()
+	k, err := utils.RandomK()
+	c.Assert(err, qt.IsNil)
+	process := &utils.Process{
+		ID:            utils.BytesToFF(util.RandomBytes(20)),
+		BallotMode:    mode,
+		EncryptionKey: pubKey,
+	}
+	process.EncryptionKeyProof, err = utils.ProveKeyKnowledge(privKey, process.ID.Bytes())
+	c.Assert(err, qt.IsNil)
+	voter := &utils.Voter{
+		Address: utils.BytesToFF(util.RandomBytes(20)),
+		Weight:  weight,
+		K:       k,
+	}
+	return process, voter
+}
+
+func TestBallotProofInputs(t *testing.T) {
+	c := qt.New(t)
+
+	mode := &utils.BallotMode{
+		MaxCount:     5,
+		MaxValue:     16,
+		MaxTotalCost: big.NewInt(1280),
+		MinTotalCost: big.NewInt(5),
+		CostExp:      2,
+	}
+	fields := []*big.Int{big.NewInt(3), big.NewInt(5), big.NewInt(2), big.NewInt(4), big.NewInt(1)}
+	process, voter := testProcessAndVoter(c, mode, big.NewInt(1))
+	for _, variant := range []utils.BallotProofVariant{
+		utils.BallotProofPlain, utils.BallotProofMiMC, utils.BallotProofPoseidon,
+	} {
+		inputs, err := utils.NewBallotProofInputs(variant, 8, fields, process, voter)
+		c.Assert(err, qt.IsNil)
+		// the derived inputs match the ones computed by hand
+		voteID, err := utils.VoteID(process.ID, voter.Address, voter.K)
+		c.Assert(err, qt.IsNil)
+		c.Assert(inputs.VoteID.Cmp(voteID), qt.Equals, 0)
+		cipherfields, plainCipherfields := utils.CipherBallotFields(fields, 8, process.EncryptionKey, voter.K)
+		c.Assert(inputs.Cipherfields, qt.DeepEquals, cipherfields)
+		preimage := []*big.Int{process.ID}
+		preimage = append(preimage, mode.BigInts()...)
+		preimage = append(preimage, process.EncryptionKey.X, process.EncryptionKey.Y, voter.Address, voteID)
+		preimage = append(preimage, plainCipherfields...)
+		preimage = append(preimage, voter.Weight)
+		inputsPreimage, err := inputs.InputsHashPreimage()
+		c.Assert(err, qt.IsNil)
+		c.Assert(inputsPreimage, qt.HasLen, len(preimage))
+		c.Assert(utils.BigIntArrayToStringArray(inputsPreimage, len(preimage)), qt.DeepEquals,
+			utils.BigIntArrayToStringArray(preimage, len(preimage)))
+		switch variant {
+		case utils.BallotProofPlain:
+			c.Assert(inputs.InputsHash, qt.IsNil)
+		case utils.BallotProofMiMC:
+			expected, err := mimc7.Hash(preimage, nil)
+			c.Assert(err, qt.IsNil)
+			c.Assert(inputs.InputsHash.Cmp(expected), qt.Equals, 0)
+		case utils.BallotProofPoseidon:
+			expected, err := utils.MultiPoseidon(preimage...)
+			c.Assert(err, qt.IsNil)
+			c.Assert(inputs.InputsHash.Cmp(expected), qt.Equals, 0)
+		}
+		// the JSON contains every signal of the circuit and can be parsed by
+		// the witness calculator
+		bInputs, err := json.Marshal(inputs)
+		c.Assert(err, qt.IsNil)
+		parsed, err := witness.ParseInputs(bInputs)
+		c.Assert(err, qt.IsNil)
+		signals := []string{
+			"fields", "max_count", "force_uniqueness", "max_value", "min_value",
+			"max_total_cost", "min_total_cost", "cost_exp", "cost_from_weight",
+			"address", "weight", "process_id", "vote_id", "pk", "k", "cipherfields",
+		}
+		if variant != utils.BallotProofPlain {
+			signals = append(signals, "inputs_hash")
+		}
+		c.Assert(parsed, qt.HasLen, len(signals))
+		for _, signal := range signals {
+			_, ok := parsed[signal]
+			c.Assert(ok, qt.IsTrue, qt.Commentf("signal %s", signal))
+		}
+	}
+}
+
+func TestBallotProofInputsValidation(t *testing.T) {
+	c := qt.New(t)
+
+	mode := &utils.BallotMode{MaxCount: 3, MaxValue: 5, MaxTotalCost: big.NewInt(10), CostExp: 1}
+	process, voter := testProcessAndVoter(c, mode, nil)
+	// invalid ballots fail fast
+	_, err := utils.NewBallotProofInputs(utils.BallotProofPoseidon, 8,
+		[]*big.Int{big.NewInt(5), big.NewInt(5), big.NewInt(5)}, process, voter)
+	c.Assert(err, qt.ErrorAs, new(*utils.BallotError))
+	// misconfigured processes fail fast
+	_, err = utils.NewBallotProofInputs(utils.BallotProofPoseidon, 2,
+		[]*big.Int{big.NewInt(1)}, process, voter)
+	c.Assert(err, qt.Not(qt.IsNil))
+	// the voter address is required
+	address := voter.Address
+	voter.Address = nil
+	_, err = utils.NewBallotProofInputs(utils.BallotProofPoseidon, 8,
+		[]*big.Int{big.NewInt(1)}, process, voter)
+	c.Assert(err, qt.ErrorMatches, "missing voter address")
+	voter.Address = address
+	// the encryption key proof is required and verified
+	inputs, err := utils.NewBallotProofInputs(utils.BallotProofPoseidon, 8,
+		[]*big.Int{big.NewInt(1)}, process, voter)
+	c.Assert(err, qt.IsNil)
+	// a missing weight is zero
+	c.Assert(inputs.Weight.Sign(), qt.Equals, 0)
+	keyProof := process.EncryptionKeyProof
+	process.EncryptionKeyProof = nil
+	_, err = utils.NewBallotProofInputs(utils.BallotProofPoseidon, 8,
+		[]*big.Int{big.NewInt(1)}, process, voter)
+	c.Assert(err, qt.ErrorMatches, "missing encryption key proof")
+	process.EncryptionKeyProof = keyProof
+	_, process.EncryptionKey = utils.GenerateKeyPair()
+	_, err = utils.NewBallotProofInputs(utils.BallotProofPoseidon, 8,
+		[]*big.Int{big.NewInt(1)}, process, voter)
+	c.Assert(err, qt.ErrorMatches, "invalid encryption key proof: .*")
+	// unless the process opts out, like the ones of the trustees joint key
+	process.SkipEncryptionKeyProof = true
+	_, err = utils.NewBallotProofInputs(utils.BallotProofPoseidon, 8,
+		[]*big.Int{big.NewInt(1)}, process, voter)
+	c.Assert(err, qt.IsNil)
+	// the encryption key must be in the curve subgroup, (0, -1) has order 2
+	process.EncryptionKey = &babyjub.PublicKey{X: big.NewInt(0), Y: new(big.Int).Sub(constants.Q, big.NewInt(1))}
+	_, err = utils.NewBallotProofInputs(utils.BallotProofPoseidon, 8,
+		[]*big.Int{big.NewInt(1)}, process, voter)
+	c.Assert(err, qt.ErrorMatches, "encryption key not in curve subgroup")
+	// the inputs of invalid ballots can be built for negative testing
+	inputsHash := inputs.InputsHash
+	inputs.Fields[0] = big.NewInt(11)
+	c.Assert(inputs.Recompute(), qt.IsNil)
+	c.Assert(inputs.InputsHash.Cmp(inputsHash), qt.Not(qt.Equals), 0)
+}
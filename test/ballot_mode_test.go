@@ -0,0 +1,164 @@
+package test
+
+import (
+	"math/big"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/z-ircuits/utils"
+)
+
+func TestBallotModeValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		mode    utils.BallotMode
+		nFields int
+		valid   bool
+	}{
+		{
+			name: "Quadratic voting – valid",
+			mode: utils.BallotMode{
+				MaxCount:     5,
+				MaxValue:     16,
+				MaxTotalCost: big.NewInt(1125),
+				MinTotalCost: big.NewInt(5),
+				CostExp:      2,
+			},
+			nFields: 8,
+			valid:   true,
+		},
+		{
+			name: "Ranked choice with unique values – valid",
+			mode: utils.BallotMode{
+				MaxCount:        3,
+				ForceUniqueness: true,
+				MaxValue:        3,
+				MinValue:        1,
+				MaxTotalCost:    big.NewInt(6),
+				MinTotalCost:    big.NewInt(6),
+				CostExp:         1,
+			},
+			nFields: 8,
+			valid:   true,
+		},
+		{
+			name: "Unbounded total cost – valid",
+			mode: utils.BallotMode{
+				MaxCount: 3,
+				MaxValue: 50,
+				CostExp:  1,
+			},
+			nFields: 8,
+			valid:   true,
+		},
+		{
+			name: "Cost from weight ignores max total cost – valid",
+			mode: utils.BallotMode{
+				MaxCount:       3,
+				MaxValue:       10,
+				MaxTotalCost:   big.NewInt(1),
+				MinTotalCost:   big.NewInt(2),
+				CostExp:        1,
+				CostFromWeight: true,
+			},
+			nFields: 8,
+			valid:   true,
+		},
+		{
+			name:    "Max count exceeds the number of fields – invalid",
+			mode:    utils.BallotMode{MaxCount: 9, MaxValue: 5, CostExp: 1},
+			nFields: 8,
+		},
+		{
+			name:    "Zero max count – invalid",
+			mode:    utils.BallotMode{MaxValue: 5, CostExp: 1},
+			nFields: 8,
+		},
+		{
+			name:    "Min value exceeds max value – invalid",
+			mode:    utils.BallotMode{MaxCount: 3, MaxValue: 5, MinValue: 6, CostExp: 1},
+			nFields: 8,
+		},
+		{
+			name:    "Max value exceeds 32 bits – invalid",
+			mode:    utils.BallotMode{MaxCount: 3, MaxValue: 1 << 32, CostExp: 1},
+			nFields: 8,
+		},
+		{
+			name:    "Not enough values for unique fields – invalid",
+			mode:    utils.BallotMode{MaxCount: 4, ForceUniqueness: true, MaxValue: 3, MinValue: 1, CostExp: 1},
+			nFields: 8,
+		},
+		{
+			name:    "Min total cost unreachable – invalid",
+			mode:    utils.BallotMode{MaxCount: 3, MaxValue: 2, MinTotalCost: big.NewInt(13), CostExp: 2},
+			nFields: 8,
+		},
+		{
+			name:    "Max total cost unreachable – invalid",
+			mode:    utils.BallotMode{MaxCount: 3, MaxValue: 5, MinValue: 2, MaxTotalCost: big.NewInt(5), CostExp: 1},
+			nFields: 8,
+		},
+		{
+			name: "Min total cost exceeds max total cost – invalid",
+			mode: utils.BallotMode{
+				MaxCount:     3,
+				MaxValue:     5,
+				MaxTotalCost: big.NewInt(5),
+				MinTotalCost: big.NewInt(6),
+				CostExp:      1,
+			},
+			nFields: 8,
+		},
+		{
+			name:    "Highest total cost exceeds 128 bits – invalid",
+			mode:    utils.BallotMode{MaxCount: 3, MaxValue: 1 << 31, CostExp: 5},
+			nFields: 8,
+		},
+		{
+			name:    "Max total cost exceeds 128 bits – invalid",
+			mode:    utils.BallotMode{MaxCount: 3, MaxValue: 5, MaxTotalCost: new(big.Int).Lsh(big.NewInt(1), 128), CostExp: 1},
+			nFields: 8,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := qt.New(t)
+			err := tc.mode.Validate(tc.nFields)
+			if tc.valid {
+				c.Assert(err, qt.IsNil)
+			} else {
+				c.Assert(err, qt.Not(qt.IsNil))
+			}
+		})
+	}
+}
+
+func TestBallotModeInputs(t *testing.T) {
+	c := qt.New(t)
+
+	mode := utils.BallotMode{
+		MaxCount:        5,
+		ForceUniqueness: true,
+		MaxValue:        16,
+		MinValue:        1,
+		MaxTotalCost:    big.NewInt(1125),
+		MinTotalCost:    big.NewInt(5),
+		CostExp:         2,
+	}
+	c.Assert(mode.CircuitInputs(), qt.DeepEquals, map[string]any{
+		"max_count":        "5",
+		"force_uniqueness": "1",
+		"max_value":        "16",
+		"min_value":        "1",
+		"max_total_cost":   "1125",
+		"min_total_cost":   "5",
+		"cost_exp":         "2",
+		"cost_from_weight": "0",
+	})
+	preimage := []string{}
+	for _, value := range mode.BigInts() {
+		preimage = append(preimage, value.String())
+	}
+	c.Assert(preimage, qt.DeepEquals, []string{"5", "1", "16", "1", "1125", "5", "2", "0"})
+}
@@ -0,0 +1,65 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/z-ircuits/utils"
+)
+
+// TestBallotProofInvalid checks that every ballot proof circuit variant
+// rejects the invalid variants of a valid ballot.
+func TestBallotProofInvalid(t *testing.T) {
+	mode := &utils.BallotMode{
+		MaxCount:        5,
+		ForceUniqueness: true,
+		MaxValue:        16,
+		MinValue:        1,
+		MaxTotalCost:    big.NewInt(500),
+		MinTotalCost:    big.NewInt(50),
+		CostExp:         2,
+	}
+	fields := []*big.Int{big.NewInt(3), big.NewInt(5), big.NewInt(2), big.NewInt(4), big.NewInt(6)}
+	for _, variant := range []utils.BallotProofVariant{
+		utils.BallotProofPlain, utils.BallotProofMiMC, utils.BallotProofPoseidon,
+	} {
+		t.Run(string(variant), func(t *testing.T) {
+			c := qt.New(t)
+			wasmFile := fmt.Sprintf("../artifacts/%s_test.wasm", variant)
+			zkeyFile := fmt.Sprintf("../artifacts/%s_test_pkey.zkey", variant)
+
+			process, voter := testProcessAndVoter(c, mode, big.NewInt(1))
+			inputs, err := utils.NewBallotProofInputs(variant, 8, fields, process, voter)
+			c.Assert(err, qt.IsNil)
+			invalid := map[string]map[string]any{}
+			// invalid ballots, with the rest of inputs consistent with them
+			ballots, err := utils.InvalidBallots(inputs.Fields, mode, inputs.Weight)
+			c.Assert(err, qt.IsNil)
+			for _, ballot := range ballots {
+				if !ballot.Enforced {
+					continue
+				}
+				mutated := *inputs
+				mutated.Fields = ballot.Fields
+				mutated.Weight = ballot.Weight
+				c.Assert(mutated.Recompute(), qt.IsNil)
+				invalid[ballot.Description] = mutated.Map()
+			}
+			// invalid proof inputs
+			proofInputs, err := utils.InvalidProofInputs(inputs)
+			c.Assert(err, qt.IsNil)
+			for _, mutated := range proofInputs {
+				invalid[mutated.Description] = mutated.Inputs
+			}
+			for description, mutated := range invalid {
+				bInputs, err := json.Marshal(mutated)
+				c.Assert(err, qt.IsNil)
+				_, _, err = utils.CompileAndGenerateProof(bInputs, wasmFile, zkeyFile)
+				c.Assert(err, qt.Not(qt.IsNil), qt.Commentf("%s", description))
+			}
+		})
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"math"
 	"math/big"
 	"os"
 	"testing"
@@ -29,79 +28,55 @@ func TestBallotProofPoseidon(t *testing.T) {
 		address   = acc.Address().Bytes()
 		processID = util.RandomBytes(20)
 		// ballot inputs
-		fields          = utils.GenerateBallotFields(5, 16, 0, false)
-		n_fields        = 8
-		maxCount        = 5
-		forceUniqueness = 0
-		maxValue        = 16
-		minValue        = 0
-		costExp         = 2
-		costFromWeight  = 0
-		weight          = 0
+		fields     = utils.GenerateBallotFields(5, 16, 0, false)
+		n_fields   = 8
+		ballotMode = &utils.BallotMode{
+			MaxCount:        5,
+			ForceUniqueness: false,
+			MaxValue:        16,
+			MinValue:        0,
+			MaxTotalCost:    big.NewInt(1280), // maxValue^costExp * maxCount
+			MinTotalCost:    big.NewInt(5),
+			CostExp:         2,
+			CostFromWeight:  false,
+		}
+		weight = big.NewInt(0)
 		// circuit assets
 		wasmFile = "../artifacts/ballot_proof_poseidon_test.wasm"
 		zkeyFile = "../artifacts/ballot_proof_poseidon_test_pkey.zkey"
 		vkeyFile = "../artifacts/ballot_proof_poseidon_test_vkey.json"
 	)
 	// encrypt ballot
-	_, pubKey := utils.GenerateKeyPair()
+	privKey, pubKey := utils.GenerateKeyPair()
 	k, err := utils.RandomK()
 	if err != nil {
 		t.Errorf("Error generating random k: %v\n", err)
 		return
 	}
-	// generate vote ID
-	bigPID := util.BigToFF(new(big.Int).SetBytes(processID))
-	bigAddr := util.BigToFF(new(big.Int).SetBytes(address))
-	voteID, err := utils.VoteID(bigPID, bigAddr, k)
+	// prove the knowledge of the encryption key for the process
+	keyProof, err := utils.ProveKeyKnowledge(privKey, utils.BytesToFF(processID).Bytes())
 	if err != nil {
-		t.Errorf("Error generating vote ID: %v\n", err)
+		t.Errorf("Error proving encryption key knowledge: %v\n", err)
 		return
 	}
-	// encrypt ballot fields and get them in plain format
-	cipherfields, plainCipherfields := utils.CipherBallotFields(fields, n_fields, pubKey, k)
-	bigInputs := []*big.Int{
-		bigPID,
-		big.NewInt(int64(maxCount)),
-		big.NewInt(int64(forceUniqueness)),
-		big.NewInt(int64(maxValue)),
-		big.NewInt(int64(minValue)),
-		big.NewInt(int64(math.Pow(float64(maxValue), float64(costExp))) * int64(maxCount)),
-		big.NewInt(int64(maxCount)),
-		big.NewInt(int64(costExp)),
-		big.NewInt(int64(costFromWeight)),
-		pubKey.X,
-		pubKey.Y,
-		bigAddr,
-		voteID,
-	}
-	bigInputs = append(bigInputs, plainCipherfields...)
-	bigInputs = append(bigInputs, big.NewInt(int64(weight)))
-	inputsHash, err := utils.MultiPoseidon(bigInputs...)
+	// circuit inputs, including the vote ID, the encrypted ballot fields and
+	// the inputs hash
+	inputs, err := utils.NewBallotProofInputs(utils.BallotProofPoseidon, n_fields, fields,
+		&utils.Process{
+			ID:                 utils.BytesToFF(processID),
+			BallotMode:         ballotMode,
+			EncryptionKey:      pubKey,
+			EncryptionKeyProof: keyProof,
+		},
+		&utils.Voter{
+			Address: utils.BytesToFF(address),
+			Weight:  weight,
+			K:       k,
+		})
 	if err != nil {
-		log.Fatalf("Error hashing: %v\n", err)
+		t.Errorf("Error building inputs: %v\n", err)
 		return
 	}
-	// circuit inputs
-	inputs := map[string]any{
-		"fields":           utils.BigIntArrayToStringArray(fields, n_fields),
-		"max_count":        fmt.Sprint(maxCount),
-		"force_uniqueness": fmt.Sprint(forceUniqueness),
-		"max_value":        fmt.Sprint(maxValue),
-		"min_value":        fmt.Sprint(minValue),
-		"max_total_cost":   fmt.Sprint(int(math.Pow(float64(maxValue), float64(costExp))) * maxCount), // (maxValue)^costExp * maxCount
-		"min_total_cost":   fmt.Sprint(maxCount),
-		"cost_exp":         fmt.Sprint(costExp),
-		"cost_from_weight": fmt.Sprint(costFromWeight),
-		"address":          bigAddr.String(),
-		"weight":           fmt.Sprint(weight),
-		"process_id":       bigPID.String(),
-		"vote_id":          voteID.String(),
-		"pk":               []string{pubKey.X.String(), pubKey.Y.String()},
-		"k":                k.String(),
-		"cipherfields":     cipherfields,
-		"inputs_hash":      inputsHash.String(),
-	}
 	bInputs, _ := json.MarshalIndent(inputs, "  ", "  ")
 	t.Log("Inputs:", string(bInputs))
 	proofData, pubSignals, err := utils.CompileAndGenerateProof(bInputs, wasmFile, zkeyFile)
@@ -121,6 +96,12 @@ func TestBallotProofPoseidon(t *testing.T) {
 		t.Errorf("Error verifying proof: %v\n", err)
 		return
 	}
+	// verify the proof against the process, the voter and the encrypted ballot
+	proof := &utils.BallotProof{Variant: utils.BallotProofPoseidon, Proof: proofData, PubSignals: pubSignals}
+	if err := utils.VerifyBallot(vkey, inputs.Process(), inputs.Voter(), inputs.EncryptedBallot(), proof); err != nil {
+		t.Errorf("Error verifying ballot: %v\n", err)
+		return
+	}
 	log.Println("Proof verified")
 	if persist {
 		// try to create the directory if it doesn't exist
@@ -2,4 +2,11 @@ pragma circom 2.1.0;
 
 include "../circuits/ballot_proof.circom";
 
-component main{public [max_count, force_uniqueness, max_value, min_value, max_total_cost, min_total_cost, cost_exp, cost_from_weight, address, process_id, vote_id, weight, cipherfields]} = BallotProof(8);
\ No newline at end of file
+// The public signals are sorted by the declaration order of the BallotProof
+// inputs, 14 + 4 * n_fields signals: max_count, force_uniqueness, max_value,
+// min_value, max_total_cost, min_total_cost, cost_exp, cost_from_weight,
+// address, weight, process_id, vote_id, pk[2] and cipherfields. The election
+// key pk is public to bind the proofs to it, so the verification keys and
+// the verifiers generated when it was private (12 + 4 * n_fields signals)
+// are not valid anymore.
+component main{public [max_count, force_uniqueness, max_value, min_value, max_total_cost, min_total_cost, cost_exp, cost_from_weight, address, process_id, vote_id, weight, pk, cipherfields]} = BallotProof(8);
\ No newline at end of file
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"math"
 	"math/big"
 	"os"
 	"testing"
@@ -34,14 +33,18 @@ func TestBallotProof(t *testing.T) {
 			big.NewInt(4),
 			big.NewInt(1),
 		}
-		n_fields        = 8
-		maxCount        = 5
-		maxValue        = 16
-		minValue        = 0
-		costExp         = 2
-		forceUniqueness = 1
-		weight          = 1
-		costFromWeight  = 0
+		n_fields   = 8
+		ballotMode = &utils.BallotMode{
+			MaxCount:        5,
+			ForceUniqueness: true,
+			MaxValue:        16,
+			MinValue:        0,
+			MaxTotalCost:    big.NewInt(1125), // (maxValue-1)^costExp * maxCount
+			MinTotalCost:    big.NewInt(5),
+			CostExp:         2,
+			CostFromWeight:  false,
+		}
+		weight = big.NewInt(1)
 		// nullifier inputs
 		address   = acc.Address().Bytes()
 		processID = util.RandomBytes(20)
@@ -51,38 +54,34 @@ func TestBallotProof(t *testing.T) {
 		vkeyFile = "../artifacts/ballot_proof_test_vkey.json"
 	)
 	// encrypt ballot
-	_, pubKey := utils.GenerateKeyPair()
+	privKey, pubKey := utils.GenerateKeyPair()
 	k, err := utils.RandomK()
 	if err != nil {
 		t.Errorf("Error generating random k: %v\n", err)
 		return
 	}
-	cipherfields, _ := utils.CipherBallotFields(fields, n_fields, pubKey, k)
-	bigPID := util.BigToFF(new(big.Int).SetBytes(processID))
-	bigAddr := util.BigToFF(new(big.Int).SetBytes(address))
-	voteID, err := utils.VoteID(bigPID, bigAddr, k)
+	// prove the knowledge of the encryption key for the process
+	keyProof, err := utils.ProveKeyKnowledge(privKey, utils.BytesToFF(processID).Bytes())
 	if err != nil {
-		t.Errorf("Error generating vote ID: %v\n", err)
+		t.Errorf("Error proving encryption key knowledge: %v\n", err)
 		return
 	}
 	// circuit inputs
-	inputs := map[string]any{
-		"fields":           utils.BigIntArrayToStringArray(fields, n_fields),
-		"max_count":        fmt.Sprint(maxCount),
-		"force_uniqueness": fmt.Sprint(forceUniqueness),
-		"max_value":        fmt.Sprint(maxValue),
-		"min_value":        fmt.Sprint(minValue),
-		"cost_exp":         fmt.Sprint(costExp),
-		"max_total_cost":   fmt.Sprint(int(math.Pow(float64(maxValue-1), float64(costExp))) * maxCount), // (maxValue-1)^costExp * maxCount
-		"min_total_cost":   fmt.Sprint(maxCount),
-		"cost_from_weight": fmt.Sprint(costFromWeight),
-		"weight":           fmt.Sprint(weight),
-		"pk":               []string{pubKey.X.String(), pubKey.Y.String()},
-		"k":                k.String(),
-		"cipherfields":     cipherfields,
-		"address":          bigAddr.String(),
-		"process_id":       bigPID.String(),
-		"vote_id":          voteID.String(),
+	inputs, err := utils.NewBallotProofInputs(utils.BallotProofPlain, n_fields, fields,
+		&utils.Process{
+			ID:                 utils.BytesToFF(processID),
+			BallotMode:         ballotMode,
+			EncryptionKey:      pubKey,
+			EncryptionKeyProof: keyProof,
+		},
+		&utils.Voter{
+			Address: utils.BytesToFF(address),
+			Weight:  weight,
+			K:       k,
+		})
+	if err != nil {
+		t.Errorf("Error building inputs: %v\n", err)
+		return
 	}
 	bInputs, _ := json.MarshalIndent(inputs, "  ", "  ")
 	t.Log("Inputs:", string(bInputs))
@@ -103,6 +102,20 @@ func TestBallotProof(t *testing.T) {
 		t.Errorf("Error verifying proof: %v\n", err)
 		return
 	}
+	// verify the proof against the process, the voter and the encrypted ballot
+	proof := &utils.BallotProof{Variant: utils.BallotProofPlain, Proof: proofData, PubSignals: pubSignals}
+	if err := utils.VerifyBallot(vkey, inputs.Process(), inputs.Voter(), inputs.EncryptedBallot(), proof); err != nil {
+		t.Errorf("Error verifying ballot: %v\n", err)
+		return
+	}
+	// the ballot is rejected for a process with another encryption key
+	_, otherKey := utils.GenerateKeyPair()
+	otherProcess := inputs.Process()
+	otherProcess.EncryptionKey = otherKey
+	if err := utils.VerifyBallot(vkey, otherProcess, inputs.Voter(), inputs.EncryptedBallot(), proof); err == nil {
+		t.Errorf("Ballot verified with another encryption key\n")
+		return
+	}
 	log.Println("Proof verified")
 	if persist {
 		if err := os.WriteFile(fmt.Sprintf("./%s_proof.json", testID), []byte(proofData), 0o644); err != nil {
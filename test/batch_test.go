@@ -0,0 +1,116 @@
+package test
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/z-ircuits/utils"
+)
+
+func TestProveBatch(t *testing.T) {
+	c := qt.New(t)
+
+	p, err := utils.NewProver(ballotProofWasmFile, ballotProofZkeyFile, 2)
+	c.Assert(err, qt.IsNil)
+	vkey, err := os.ReadFile(ballotProofVkeyFile)
+	c.Assert(err, qt.IsNil)
+
+	// every third input is invalid
+	inputs := [][]byte{}
+	for i := 0; i < 6; i++ {
+		if i%3 == 2 {
+			inputs = append(inputs, []byte(`{"fields": ["1"]}`))
+			continue
+		}
+		inputs = append(inputs, testBallotProofInputs(c))
+	}
+	progress := []utils.BatchProgress{}
+	results := p.ProveBatch(context.Background(), inputs, &utils.BatchOptions{
+		Workers:         3,
+		VerificationKey: vkey,
+		OnProgress: func(p utils.BatchProgress, _ *utils.BatchResult) {
+			progress = append(progress, p)
+		},
+	})
+	c.Assert(results, qt.HasLen, len(inputs))
+	for i, result := range results {
+		c.Assert(result.Index, qt.Equals, i)
+		if i%3 == 2 {
+			c.Assert(result.Err, qt.IsNotNil)
+			c.Assert(result.Verified, qt.IsFalse)
+			continue
+		}
+		c.Assert(result.Err, qt.IsNil)
+		c.Assert(result.Verified, qt.IsTrue)
+		c.Assert(utils.VerifyProof(result.Proof, result.PubSignals, vkey), qt.IsNil)
+	}
+	c.Assert(progress, qt.HasLen, len(inputs))
+	c.Assert(progress[len(progress)-1], qt.DeepEquals, utils.BatchProgress{Submitted: 6, Completed: 6, Failed: 2})
+
+	// an invalid verification key fails every input
+	results = p.ProveBatch(context.Background(), inputs[:2], &utils.BatchOptions{VerificationKey: []byte("{}")})
+	for _, result := range results {
+		c.Assert(result.Err, qt.ErrorMatches, "invalid verification key.*")
+	}
+}
+
+func TestProveStreamBackpressure(t *testing.T) {
+	c := qt.New(t)
+
+	p, err := utils.NewProver(ballotProofWasmFile, ballotProofZkeyFile, 3)
+	c.Assert(err, qt.IsNil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// send the inputs counting the ones read by the stream
+	const n = 5
+	var read atomic.Int32
+	inputs := make(chan []byte)
+	bInputs := testBallotProofInputs(c)
+	go func() {
+		defer close(inputs)
+		for i := 0; i < n; i++ {
+			select {
+			case inputs <- bInputs:
+				read.Add(1)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	// waitRead waits for the number of inputs read to settle and returns it
+	waitRead := func() int32 {
+		for {
+			before := read.Load()
+			time.Sleep(time.Second)
+			if after := read.Load(); after == before {
+				return after
+			}
+		}
+	}
+	// with more workers than the maximum pending results, only MaxPending
+	// inputs are read without consuming results
+	results := p.ProveStream(ctx, inputs, &utils.BatchOptions{Workers: 3, MaxPending: 2})
+	c.Assert(waitRead(), qt.Equals, int32(2))
+	// consuming a result makes room for a single new input
+	for i := 0; i < n; i++ {
+		result := <-results
+		c.Assert(result.Index, qt.Equals, i)
+		c.Assert(result.Err, qt.IsNil)
+		if i < n-2 {
+			c.Assert(waitRead(), qt.Equals, int32(i+3))
+		}
+	}
+	_, ok := <-results
+	c.Assert(ok, qt.IsFalse)
+
+	// cancelling the context closes the results
+	results = p.ProveStream(ctx, make(chan []byte), nil)
+	cancel()
+	_, ok = <-results
+	c.Assert(ok, qt.IsFalse)
+}
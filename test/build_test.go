@@ -0,0 +1,31 @@
+package test
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// TestBuildWithoutCgo checks that the packages of the module, including
+// their tests, build with CGO_ENABLED=0, using the pure Go prover backend and
+// witness calculator.
+func TestBuildWithoutCgo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping build in short mode")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	c := qt.New(t)
+	// vet also type checks the test files
+	for _, args := range [][]string{{"build", "./..."}, {"vet", "./..."}} {
+		cmd := exec.Command(goBin, args...)
+		cmd.Dir = ".."
+		cmd.Env = append(os.Environ(), "CGO_ENABLED=0")
+		out, err := cmd.CombinedOutput()
+		c.Assert(err, qt.IsNil, qt.Commentf("go %s: %s", args[0], out))
+	}
+}
@@ -0,0 +1,154 @@
+package test
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	"github.com/iden3/go-iden3-crypto/constants"
+	"github.com/vocdoni/z-ircuits/utils"
+)
+
+func TestDecrypt(t *testing.T) {
+	c := qt.New(t)
+
+	privKey, pubKey := utils.GenerateKeyPair()
+	for _, msg := range []uint64{0, 1, 3, 255, 65535, 65536, 1 << 20, utils.MaxMessage} {
+		k, err := utils.RandomK()
+		c.Assert(err, qt.IsNil)
+		c1, c2 := utils.Encrypt(new(big.Int).SetUint64(msg), pubKey, k)
+		decrypted, err := utils.Decrypt(privKey, c1, c2, utils.MaxMessage)
+		c.Assert(err, qt.IsNil)
+		c.Assert(decrypted.Uint64(), qt.Equals, msg)
+	}
+
+	// messages out of the range should not be found
+	k, err := utils.RandomK()
+	c.Assert(err, qt.IsNil)
+	c1, c2 := utils.Encrypt(big.NewInt(101), pubKey, k)
+	_, err = utils.Decrypt(privKey, c1, c2, 100)
+	c.Assert(err, qt.Not(qt.IsNil))
+	decrypted, err := utils.Decrypt(privKey, c1, c2, 101)
+	c.Assert(err, qt.IsNil)
+	c.Assert(decrypted.Int64(), qt.Equals, int64(101))
+
+	// the max can not exceed the message space of the circuit
+	_, err = utils.Decrypt(privKey, c1, c2, utils.MaxMessage+1)
+	c.Assert(err, qt.Not(qt.IsNil))
+
+	// a different key should not decrypt the message
+	otherKey, _ := utils.GenerateKeyPair()
+	_, err = utils.Decrypt(otherKey, c1, c2, 1000)
+	c.Assert(err, qt.Not(qt.IsNil))
+}
+
+func TestDecryptConcurrent(t *testing.T) {
+	c := qt.New(t)
+
+	// decrypt concurrently with more ranges than baby steps tables cached
+	privKey, pubKey := utils.GenerateKeyPair()
+	maxValues := []uint64{10, 100, 1000, 10000, 100000, 1000000}
+	var wg sync.WaitGroup
+	errs := make(chan error, 4*len(maxValues))
+	for i := 0; i < 4; i++ {
+		for _, max := range maxValues {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				k, err := utils.RandomK()
+				if err != nil {
+					errs <- err
+					return
+				}
+				c1, c2 := utils.Encrypt(new(big.Int).SetUint64(max), pubKey, k)
+				decrypted, err := utils.Decrypt(privKey, c1, c2, max)
+				if err == nil && decrypted.Uint64() != max {
+					err = fmt.Errorf("decrypted %s, expected %d", decrypted, max)
+				}
+				errs <- err
+			}()
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		c.Assert(err, qt.IsNil)
+	}
+}
+
+func TestRerandomize(t *testing.T) {
+	c := qt.New(t)
+
+	privKey, pubKey := utils.GenerateKeyPair()
+	msg := big.NewInt(42)
+	k, err := utils.RandomK()
+	c.Assert(err, qt.IsNil)
+	c1, c2 := utils.Encrypt(msg, pubKey, k)
+	r, err := utils.RandomK()
+	c.Assert(err, qt.IsNil)
+	rc1, rc2, proof, err := utils.RerandomizeWithProof(pubKey, c1, c2, r)
+	c.Assert(err, qt.IsNil)
+	// the new ciphertext is different but encrypts the same message
+	c.Assert(rc1.X.Cmp(c1.X) == 0 && rc1.Y.Cmp(c1.Y) == 0, qt.IsFalse)
+	c.Assert(rc2.X.Cmp(c2.X) == 0 && rc2.Y.Cmp(c2.Y) == 0, qt.IsFalse)
+	decrypted, err := utils.Decrypt(privKey, rc1, rc2, 100)
+	c.Assert(err, qt.IsNil)
+	c.Assert(decrypted.Cmp(msg), qt.Equals, 0)
+	// it is the same as encrypting with k + r
+	ec1, ec2 := utils.Encrypt(msg, pubKey, new(big.Int).Add(k, r))
+	c.Assert(ec1.X.Cmp(rc1.X), qt.Equals, 0)
+	c.Assert(ec2.X.Cmp(rc2.X), qt.Equals, 0)
+	// the proof is valid for the rerandomized ciphertext
+	c.Assert(utils.VerifyRerandomization(pubKey, c1, c2, rc1, rc2, proof), qt.IsNil)
+	// but not for a ciphertext of a different message
+	oc1, oc2 := utils.Encrypt(big.NewInt(43), pubKey, new(big.Int).Add(k, r))
+	c.Assert(utils.VerifyRerandomization(pubKey, c1, c2, oc1, oc2, proof), qt.Not(qt.IsNil))
+	// nor for a different original ciphertext
+	c.Assert(utils.VerifyRerandomization(pubKey, oc1, oc2, rc1, rc2, proof), qt.Not(qt.IsNil))
+}
+
+func TestDLEQ(t *testing.T) {
+	c := qt.New(t)
+
+	x, err := utils.RandomK()
+	c.Assert(err, qt.IsNil)
+	g2 := babyjub.NewPoint().Mul(big.NewInt(3), babyjub.B8)
+	h1, h2 := babyjub.NewPoint().Mul(x, babyjub.B8), babyjub.NewPoint().Mul(x, g2)
+	proof, err := utils.ProveDLEQ(x, babyjub.B8, h1, g2, h2)
+	c.Assert(err, qt.IsNil)
+	c.Assert(utils.VerifyDLEQ(proof, babyjub.B8, h1, g2, h2), qt.IsNil)
+	// the proof is bound to its points
+	c.Assert(utils.VerifyDLEQ(proof, babyjub.B8, h1, g2, h1), qt.ErrorMatches, "invalid proof")
+	// a response that is not reduced to the subgroup order is rejected
+	unreduced := &utils.DLEQProof{
+		Challenge: proof.Challenge,
+		Response:  new(big.Int).Add(proof.Response, babyjub.SubOrder),
+	}
+	c.Assert(utils.VerifyDLEQ(unreduced, babyjub.B8, h1, g2, h2), qt.ErrorMatches, "response out of range")
+	// points out of the curve subgroup are rejected, (0, -1) has order 2
+	lowOrder := &babyjub.Point{X: big.NewInt(0), Y: new(big.Int).Sub(constants.Q, big.NewInt(1))}
+	h2LowOrder := babyjub.NewPointProjective().Add(h2.Projective(), lowOrder.Projective()).Affine()
+	c.Assert(utils.VerifyDLEQ(proof, babyjub.B8, h1, g2, h2LowOrder), qt.ErrorMatches,
+		"point not in curve subgroup")
+}
+
+func TestDecryptionProof(t *testing.T) {
+	c := qt.New(t)
+
+	privKey, pubKey := utils.GenerateKeyPair()
+	k, err := utils.RandomK()
+	c.Assert(err, qt.IsNil)
+	c1, c2 := utils.Encrypt(big.NewInt(7), pubKey, k)
+	msg, proof, err := utils.DecryptWithProof(privKey, c1, c2, 100)
+	c.Assert(err, qt.IsNil)
+	c.Assert(msg.Int64(), qt.Equals, int64(7))
+	c.Assert(utils.VerifyDecryption(pubKey, c1, c2, msg, proof), qt.IsNil)
+	// a different result is rejected
+	c.Assert(utils.VerifyDecryption(pubKey, c1, c2, big.NewInt(8), proof), qt.Not(qt.IsNil))
+	// a different key is rejected
+	_, otherKey := utils.GenerateKeyPair()
+	c.Assert(utils.VerifyDecryption(otherKey, c1, c2, msg, proof), qt.Not(qt.IsNil))
+}
@@ -0,0 +1,246 @@
+package evm
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	qt "github.com/frankban/quicktest"
+	solc "github.com/rxtech-lab/solc-go"
+	"github.com/vocdoni/z-ircuits/utils"
+	"github.com/vocdoni/z-ircuits/utils/groth16"
+)
+
+// solcVersion is the version of the Solidity compiler used to compile the
+// verifiers, one of the versions embedded in solc-go, so no download is
+// needed, and solcEVMVersion is the EVM version targeted.
+const (
+	solcVersion    = "0.8.30"
+	solcEVMVersion = "paris"
+)
+
+var (
+	solcCompiler     solc.Solc
+	solcCompilerErr  error
+	solcCompilerOnce sync.Once
+	solcCompilerLock sync.Mutex
+)
+
+// compileVerifier returns the deployment bytecode of the Solidity verifier
+// generated for the snarkjs verification key provided.
+func compileVerifier(c *qt.C, vkey []byte) []byte {
+	source, err := groth16.SolidityVerifier(vkey, "")
+	c.Assert(err, qt.IsNil)
+	solcCompilerOnce.Do(func() {
+		solcCompiler, solcCompilerErr = solc.NewWithVersion(solcVersion)
+	})
+	c.Assert(solcCompilerErr, qt.IsNil)
+	solcCompilerLock.Lock()
+	defer solcCompilerLock.Unlock()
+	output, err := solcCompiler.CompileWithOptions(&solc.Input{
+		Language: "Solidity",
+		Sources:  map[string]solc.SourceIn{"Verifier.sol": {Content: source}},
+		Settings: solc.Settings{
+			Optimizer: solc.Optimizer{Enabled: true, Runs: 200},
+			// the simulated chain does not support the PUSH0 opcode of
+			// shanghai
+			EVMVersion: solcEVMVersion,
+			OutputSelection: map[string]map[string][]string{
+				"*": {"*": {"evm.bytecode.object"}},
+			},
+		},
+	}, nil)
+	c.Assert(err, qt.IsNil)
+	for _, e := range output.Errors {
+		c.Assert(e.Severity, qt.Not(qt.Equals), "error", qt.Commentf("%s", e.FormattedMessage))
+	}
+	bytecode, err := hex.DecodeString(output.Contracts["Verifier.sol"][groth16.DefaultSolidityVerifierName].EVM.Bytecode.Object)
+	c.Assert(err, qt.IsNil)
+	c.Assert(bytecode, qt.Not(qt.HasLen), 0)
+	return bytecode
+}
+
+// evmVerifier is a Solidity verifier deployed on a simulated chain.
+type evmVerifier struct {
+	c        *qt.C
+	backend  *simulated.Backend
+	auth     *bind.TransactOpts
+	address  common.Address
+	contract *bind.BoundContract
+	nPublic  int
+}
+
+// newEVMVerifier compiles the Solidity verifier of the snarkjs verification
+// key provided and deploys it on a new simulated chain.
+func newEVMVerifier(c *qt.C, vkey []byte) *evmVerifier {
+	bytecode := compileVerifier(c, vkey)
+	key, err := crypto.GenerateKey()
+	c.Assert(err, qt.IsNil)
+	auth, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+	c.Assert(err, qt.IsNil)
+	balance, _ := new(big.Int).SetString("1000000000000000000000", 10)
+	backend := simulated.NewBackend(types.GenesisAlloc{auth.From: {Balance: balance}})
+	c.Cleanup(func() { c.Assert(backend.Close(), qt.IsNil) })
+
+	vk, err := groth16.ParseVerificationKey(vkey)
+	c.Assert(err, qt.IsNil)
+	address, _, contract, err := bind.DeployContract(auth, verifyProofABI(c, vk.NPublic), bytecode, backend.Client())
+	c.Assert(err, qt.IsNil)
+	backend.Commit()
+	code, err := backend.Client().CodeAt(context.Background(), address, nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(code, qt.Not(qt.HasLen), 0)
+	return &evmVerifier{c: c, backend: backend, auth: auth, address: address, contract: contract, nPublic: vk.NPublic}
+}
+
+// verify calls the verifyProof function of the contract with the calldata
+// provided, returning its result.
+func (v *evmVerifier) verify(calldata *groth16.SolidityCalldata) bool {
+	res, err := v.backend.Client().CallContract(context.Background(), ethereum.CallMsg{
+		To:   &v.address,
+		Data: calldata.Pack(),
+	}, nil)
+	v.c.Assert(err, qt.IsNil)
+	v.c.Assert(res, qt.HasLen, 32)
+	return new(big.Int).SetBytes(res).Cmp(big.NewInt(1)) == 0
+}
+
+// gasUsed sends a transaction to the verifyProof function of the contract
+// with the calldata provided, returning the gas used by it, including the
+// intrinsic and the calldata costs.
+func (v *evmVerifier) gasUsed(calldata *groth16.SolidityCalldata) uint64 {
+	tx, err := v.contract.RawTransact(v.auth, calldata.Pack())
+	v.c.Assert(err, qt.IsNil)
+	v.backend.Commit()
+	receipt, err := v.backend.Client().TransactionReceipt(context.Background(), tx.Hash())
+	v.c.Assert(err, qt.IsNil)
+	v.c.Assert(receipt.Status, qt.Equals, types.ReceiptStatusSuccessful)
+	return receipt.GasUsed
+}
+
+// checkEVMVerifier checks that the verifier accepts the proof provided and
+// rejects its invalid variants, returning the gas used to verify it.
+func checkEVMVerifier(c *qt.C, v *evmVerifier, proof, pubSignals string) uint64 {
+	calldata, err := groth16.NewSolidityCalldata(proof, pubSignals)
+	c.Assert(err, qt.IsNil)
+	c.Assert(calldata.Input, qt.HasLen, v.nPublic)
+	c.Assert(v.verify(calldata), qt.IsTrue)
+
+	invalid := map[string]func(cd *groth16.SolidityCalldata){
+		"modified signal": func(cd *groth16.SolidityCalldata) {
+			cd.Input[0] = new(big.Int).Add(cd.Input[0], big.NewInt(1))
+		},
+		"non canonical signal": func(cd *groth16.SolidityCalldata) {
+			cd.Input[0] = new(big.Int).Add(cd.Input[0], fr.Modulus())
+		},
+		"swapped points": func(cd *groth16.SolidityCalldata) {
+			cd.A, cd.C = cd.C, cd.A
+		},
+		"B not swapped": func(cd *groth16.SolidityCalldata) {
+			cd.B[0][0], cd.B[0][1] = cd.B[0][1], cd.B[0][0]
+			cd.B[1][0], cd.B[1][1] = cd.B[1][1], cd.B[1][0]
+		},
+		"point not in curve": func(cd *groth16.SolidityCalldata) {
+			cd.C[1] = big.NewInt(1)
+		},
+	}
+	for description, mutate := range invalid {
+		mutated, err := groth16.NewSolidityCalldata(proof, pubSignals)
+		c.Assert(err, qt.IsNil)
+		mutate(mutated)
+		c.Assert(v.verify(mutated), qt.IsFalse, qt.Commentf("%s", description))
+	}
+	return v.gasUsed(calldata)
+}
+
+// TestSolidityVerifierEVM checks the Solidity verifiers on a simulated chain
+// with proofs built from random verification keys, for the number of public
+// signals of the ballot proof circuit variants.
+func TestSolidityVerifierEVM(t *testing.T) {
+	for _, nPublic := range []int{1, 46} {
+		t.Run(fmt.Sprintf("%d public signals", nPublic), func(t *testing.T) {
+			c := qt.New(t)
+			vkey, proofs, pubSignals := testGroth16Proofs(c, nPublic, 2)
+			v := newEVMVerifier(c, vkey)
+			gas := checkEVMVerifier(c, v, proofs[0], pubSignals[0])
+			c.Assert(v.verify(mustSolidityCalldata(c, proofs[1], pubSignals[1])), qt.IsTrue)
+			c.Assert(v.verify(mustSolidityCalldata(c, proofs[0], pubSignals[1])), qt.IsFalse)
+			// the proofs of other verification keys are rejected
+			_, otherProofs, otherPubSignals := testGroth16Proofs(c, nPublic, 1)
+			c.Assert(v.verify(mustSolidityCalldata(c, otherProofs[0], otherPubSignals[0])), qt.IsFalse)
+			c.Logf("verifyProof gas used with %d public signals: %d", nPublic, gas)
+		})
+	}
+}
+
+// TestBallotProofEVM checks that the proofs of the ballot proof circuit
+// variants generated with every prover backend verify on a simulated chain,
+// and reports the gas used by each variant.
+func TestBallotProofEVM(t *testing.T) {
+	mode := &utils.BallotMode{
+		MaxCount:     5,
+		MaxValue:     16,
+		MaxTotalCost: big.NewInt(1280),
+		MinTotalCost: big.NewInt(5),
+		CostExp:      2,
+	}
+	fields := []*big.Int{big.NewInt(3), big.NewInt(5), big.NewInt(2), big.NewInt(4), big.NewInt(1)}
+	gasReport := map[utils.BallotProofVariant]uint64{}
+	variants := []utils.BallotProofVariant{
+		utils.BallotProofPlain, utils.BallotProofMiMC, utils.BallotProofPoseidon,
+	}
+	for _, variant := range variants {
+		t.Run(string(variant), func(t *testing.T) {
+			c := qt.New(t)
+			wasmFile := fmt.Sprintf("../../artifacts/%s_test.wasm", variant)
+			zkeyFile := fmt.Sprintf("../../artifacts/%s_test_pkey.zkey", variant)
+			vkeyFile := fmt.Sprintf("../../artifacts/%s_test_vkey.json", variant)
+			vkey, err := os.ReadFile(vkeyFile)
+			c.Assert(err, qt.IsNil)
+			v := newEVMVerifier(c, vkey)
+
+			process, voter := testProcessAndVoter(c, mode, big.NewInt(1))
+			inputs, err := utils.NewBallotProofInputs(variant, 8, fields, process, voter)
+			c.Assert(err, qt.IsNil)
+			bInputs, err := json.Marshal(inputs)
+			c.Assert(err, qt.IsNil)
+			proof, pubSignals, err := utils.CompileAndGenerateProof(bInputs, wasmFile, zkeyFile)
+			c.Assert(err, qt.IsNil)
+			gasReport[variant] = checkEVMVerifier(c, v, proof, pubSignals)
+
+			// the proofs of the pure Go backend verify too
+			wasm, err := os.ReadFile(wasmFile)
+			c.Assert(err, qt.IsNil)
+			zkey, err := os.ReadFile(zkeyFile)
+			c.Assert(err, qt.IsNil)
+			p, err := utils.NewProverWithBackend(wasm, zkey, 1, &utils.GoBackend{})
+			c.Assert(err, qt.IsNil)
+			proof, pubSignals, err = p.Prove(context.Background(), bInputs)
+			c.Assert(err, qt.IsNil)
+			c.Assert(v.verify(mustSolidityCalldata(c, proof, pubSignals)), qt.IsTrue)
+		})
+	}
+	for _, variant := range variants {
+		if gas, ok := gasReport[variant]; ok {
+			t.Logf("%s verifyProof gas used: %d", variant, gas)
+		}
+	}
+}
+
+func mustSolidityCalldata(c *qt.C, proof, pubSignals string) *groth16.SolidityCalldata {
+	calldata, err := groth16.NewSolidityCalldata(proof, pubSignals)
+	c.Assert(err, qt.IsNil)
+	return calldata
+}
@@ -0,0 +1,141 @@
+package evm
+
+// The helpers of this file are copies of the ones of the test package, which
+// is part of the main module.
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/z-ircuits/utils"
+	"go.vocdoni.io/dvote/util"
+)
+
+func g1Strings(p *bn254.G1Affine) []string {
+	return []string{p.X.String(), p.Y.String(), "1"}
+}
+
+func g2Strings(p *bn254.G2Affine) [][]string {
+	return [][]string{{p.X.A0.String(), p.X.A1.String()}, {p.Y.A0.String(), p.Y.A1.String()}, {"1", "0"}}
+}
+
+// testGroth16Proofs returns a random snarkjs verification key of nPublic
+// public signals and n valid proofs of it for random public signals. The
+// proofs are built from the trapdoor of the key instead of a circuit, so
+// they do not need the artifacts.
+func testGroth16Proofs(c *qt.C, nPublic, n int) ([]byte, []string, []string) {
+	_, _, g1, g2 := bn254.Generators()
+	random := func() fr.Element {
+		var e fr.Element
+		_, err := e.SetRandom()
+		c.Assert(err, qt.IsNil)
+		return e
+	}
+	mulG1 := func(e fr.Element) bn254.G1Affine {
+		var p bn254.G1Affine
+		return *p.ScalarMultiplication(&g1, e.BigInt(new(big.Int)))
+	}
+	mulG2 := func(e fr.Element) bn254.G2Affine {
+		var p bn254.G2Affine
+		return *p.ScalarMultiplication(&g2, e.BigInt(new(big.Int)))
+	}
+
+	alpha, beta, gamma, delta := random(), random(), random(), random()
+	ic := make([]fr.Element, nPublic+1)
+	icPoints := make([][]string, nPublic+1)
+	for i := range ic {
+		ic[i] = random()
+		p := mulG1(ic[i])
+		icPoints[i] = g1Strings(&p)
+	}
+	alpha1, beta2, gamma2, delta2 := mulG1(alpha), mulG2(beta), mulG2(gamma), mulG2(delta)
+	vkey, err := json.Marshal(map[string]any{
+		"protocol":   "groth16",
+		"curve":      "bn128",
+		"nPublic":    nPublic,
+		"vk_alpha_1": g1Strings(&alpha1),
+		"vk_beta_2":  g2Strings(&beta2),
+		"vk_gamma_2": g2Strings(&gamma2),
+		"vk_delta_2": g2Strings(&delta2),
+		"IC":         icPoints,
+	})
+	c.Assert(err, qt.IsNil)
+
+	// e(A, B) = e(alpha, beta) * e(vk_x, gamma) * e(C, delta) holds for
+	// a * b = alpha * beta + x * gamma + c * delta
+	var alphaBeta, deltaInv fr.Element
+	alphaBeta.Mul(&alpha, &beta)
+	deltaInv.Inverse(&delta)
+	proofs := make([]string, n)
+	pubSignals := make([]string, n)
+	for i := range proofs {
+		x := ic[0]
+		signals := make([]string, nPublic)
+		for j := range signals {
+			s := random()
+			signals[j] = s.String()
+			s.Mul(&s, &ic[j+1])
+			x.Add(&x, &s)
+		}
+		a, b := random(), random()
+		var cs fr.Element
+		cs.Mul(&a, &b)
+		cs.Sub(&cs, &alphaBeta)
+		x.Mul(&x, &gamma)
+		cs.Sub(&cs, &x)
+		cs.Mul(&cs, &deltaInv)
+		pa, pb, pc := mulG1(a), mulG2(b), mulG1(cs)
+		bProof, err := json.Marshal(utils.ProofData{
+			A: g1Strings(&pa), B: g2Strings(&pb), C: g1Strings(&pc), Protocol: "groth16", Curve: "bn128",
+		})
+		c.Assert(err, qt.IsNil)
+		bSignals, err := json.Marshal(signals)
+		c.Assert(err, qt.IsNil)
+		proofs[i], pubSignals[i] = string(bProof), string(bSignals)
+	}
+	return vkey, proofs, pubSignals
+}
+
+// verifyProofABI returns the ABI of the verifyProof function of the Solidity
+// verifiers of nPublic public signals.
+func verifyProofABI(c *qt.C, nPublic int) abi.ABI {
+	contractABI, err := abi.JSON(strings.NewReader(fmt.Sprintf(`[{
+		"type": "function", "name": "verifyProof", "stateMutability": "view",
+		"inputs": [
+			{"name": "_pA", "type": "uint256[2]"},
+			{"name": "_pB", "type": "uint256[2][2]"},
+			{"name": "_pC", "type": "uint256[2]"},
+			{"name": "_pubSignals", "type": "uint256[%d]"}
+		],
+		"outputs": [{"name": "", "type": "bool"}]
+	}]`, nPublic)))
+	c.Assert(err, qt.IsNil)
+	return contractABI
+}
+
+// testProcessAndVoter returns a random process and voter for the ballot mode
+// provided.
+func testProcessAndVoter(c *qt.C, mode *utils.BallotMode, weight *big.Int) (*utils.Process, *utils.Voter) {
+	privKey, pubKey := utils.GenerateKeyPair()
+	k, err := utils.RandomK()
+	c.Assert(err, qt.IsNil)
+	process := &utils.Process{
+		ID:            utils.BytesToFF(util.RandomBytes(20)),
+		BallotMode:    mode,
+		EncryptionKey: pubKey,
+	}
+	process.EncryptionKeyProof, err = utils.ProveKeyKnowledge(privKey, process.ID.Bytes())
+	c.Assert(err, qt.IsNil)
+	voter := &utils.Voter{
+		Address: utils.BytesToFF(util.RandomBytes(20)),
+		Weight:  weight,
+		K:       k,
+	}
+	return process, voter
+}
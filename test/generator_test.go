@@ -0,0 +1,109 @@
+package test
+
+import (
+	"errors"
+	"math/big"
+	"math/rand/v2"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/z-ircuits/utils"
+)
+
+func TestBallotGenerator(t *testing.T) {
+	modes := []*utils.BallotMode{
+		{MaxCount: 5, MaxValue: 16, MaxTotalCost: big.NewInt(1125), MinTotalCost: big.NewInt(5), CostExp: 2},
+		{MaxCount: 5, ForceUniqueness: true, MaxValue: 16, MaxTotalCost: big.NewInt(100), MinTotalCost: big.NewInt(50), CostExp: 2},
+		{MaxCount: 3, ForceUniqueness: true, MaxValue: 3, MinValue: 1, MaxTotalCost: big.NewInt(6), MinTotalCost: big.NewInt(6), CostExp: 1},
+		{MaxCount: 6, MaxValue: 1, MaxTotalCost: big.NewInt(3), MinTotalCost: big.NewInt(3), CostExp: 1},
+		{MaxCount: 8, MaxValue: 1 << 20, CostExp: 1},
+		{MaxCount: 4, MaxValue: 10, MinValue: 2, MaxTotalCost: big.NewInt(1), MinTotalCost: big.NewInt(100), CostExp: 3, CostFromWeight: true},
+		{MaxCount: 8, ForceUniqueness: true, MaxValue: utils.MaxMessage, MinTotalCost: big.NewInt(1 << 40), CostExp: 2},
+	}
+	weight := big.NewInt(2000)
+	for i, mode := range modes {
+		c := qt.New(t)
+		gen, err := utils.NewBallotGenerator(mode, 8, weight, rand.NewPCG(1, uint64(i)))
+		c.Assert(err, qt.IsNil)
+		for j := 0; j < 50; j++ {
+			fields, err := gen.Random()
+			c.Assert(err, qt.IsNil)
+			c.Assert(fields, qt.HasLen, 8)
+			c.Assert(utils.CheckBallot(fields, mode, weight), qt.IsNil, qt.Commentf("mode %d: %v", i, fields))
+		}
+		edges := gen.EdgeCases()
+		c.Assert(len(edges) > 0, qt.IsTrue, qt.Commentf("mode %d", i))
+		for _, fields := range edges {
+			c.Assert(utils.CheckBallot(fields, mode, weight), qt.IsNil, qt.Commentf("mode %d: %v", i, fields))
+		}
+	}
+}
+
+func TestBallotGeneratorEdgeCases(t *testing.T) {
+	c := qt.New(t)
+
+	// with a linear cost the edge cases reach the cost bounds exactly
+	mode := &utils.BallotMode{
+		MaxCount:     4,
+		MaxValue:     10,
+		MaxTotalCost: big.NewInt(25),
+		MinTotalCost: big.NewInt(7),
+		CostExp:      1,
+	}
+	gen, err := utils.NewBallotGenerator(mode, 8, nil, rand.NewPCG(1, 2))
+	c.Assert(err, qt.IsNil)
+	costs := map[int64]bool{}
+	for _, fields := range gen.EdgeCases() {
+		costs[utils.TotalCost(fields[:4], mode.CostExp).Int64()] = true
+	}
+	c.Assert(costs[7], qt.IsTrue)
+	c.Assert(costs[25], qt.IsTrue)
+}
+
+func TestBallotGeneratorSeed(t *testing.T) {
+	c := qt.New(t)
+
+	mode := &utils.BallotMode{MaxCount: 5, MaxValue: 16, MaxTotalCost: big.NewInt(500), CostExp: 2}
+	genA, err := utils.NewBallotGenerator(mode, 8, nil, rand.NewPCG(42, 42))
+	c.Assert(err, qt.IsNil)
+	genB, err := utils.NewBallotGenerator(mode, 8, nil, rand.NewPCG(42, 42))
+	c.Assert(err, qt.IsNil)
+	for i := 0; i < 10; i++ {
+		a, err := genA.Random()
+		c.Assert(err, qt.IsNil)
+		b, err := genB.Random()
+		c.Assert(err, qt.IsNil)
+		c.Assert(utils.BigIntArrayToStringArray(a, 8), qt.DeepEquals, utils.BigIntArrayToStringArray(b, 8))
+	}
+	// unsatisfiable configurations are reported as errors
+	_, err = utils.NewBallotGenerator(&utils.BallotMode{MaxCount: 9, MaxValue: 16, CostExp: 1}, 8, nil, nil)
+	c.Assert(err, qt.Not(qt.IsNil))
+	fromWeight := &utils.BallotMode{MaxCount: 3, MaxValue: 16, MinValue: 5, MaxTotalCost: big.NewInt(1), CostExp: 1, CostFromWeight: true}
+	_, err = utils.NewBallotGenerator(fromWeight, 8, big.NewInt(10), nil)
+	c.Assert(err, qt.Not(qt.IsNil))
+}
+
+func TestBallotGeneratorWeight(t *testing.T) {
+	c := qt.New(t)
+
+	// without max total cost the weight does not bound the cost, but it is
+	// still an input of the comparator
+	mode := &utils.BallotMode{MaxCount: 3, MaxValue: 16, CostExp: 1, CostFromWeight: true}
+	weight := big.NewInt(5)
+	gen, err := utils.NewBallotGenerator(mode, 8, weight, rand.NewPCG(1, 3))
+	c.Assert(err, qt.IsNil)
+	for i := 0; i < 20; i++ {
+		fields, err := gen.Random()
+		c.Assert(err, qt.IsNil)
+		c.Assert(utils.CheckBallot(fields, mode, weight), qt.IsNil)
+	}
+	// a weight out of the comparator range is reported up front
+	outOfRange := new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), utils.MaxCostBits), big.NewInt(14))
+	for _, maxTotalCost := range []*big.Int{nil, big.NewInt(1)} {
+		mode := &utils.BallotMode{MaxCount: 3, MaxValue: 16, MaxTotalCost: maxTotalCost, CostExp: 1, CostFromWeight: true}
+		_, err := utils.NewBallotGenerator(mode, 8, outOfRange, nil)
+		var ballotErr *utils.BallotError
+		c.Assert(errors.As(err, &ballotErr), qt.IsTrue, qt.Commentf("%v", err))
+		c.Assert(ballotErr.Rule, qt.Equals, utils.RuleWeightSize)
+	}
+}
@@ -0,0 +1,98 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/z-ircuits/utils"
+	"github.com/vocdoni/z-ircuits/utils/groth16"
+)
+
+// testBatchProofs returns the proofs and public signals provided as a
+// batch.
+func testBatchProofs(proofs, pubSignals []string) []*groth16.Proof {
+	batch := make([]*groth16.Proof, len(proofs))
+	for i := range proofs {
+		batch[i] = &groth16.Proof{Proof: proofs[i], PubSignals: pubSignals[i]}
+	}
+	return batch
+}
+
+func TestVerifyBatch(t *testing.T) {
+	c := qt.New(t)
+
+	vkey, proofs, pubSignals := testGroth16Proofs(c, 2, 16)
+	vk, err := groth16.ParseVerificationKey(vkey)
+	c.Assert(err, qt.IsNil)
+	c.Assert(vk.VerifyBatch(nil), qt.IsNil)
+	c.Assert(vk.VerifyBatch(testBatchProofs(proofs, pubSignals)), qt.IsNil)
+	c.Assert(groth16.VerifyBatch(vkey, testBatchProofs(proofs, pubSignals)), qt.IsNil)
+
+	// the invalid proofs are found, in order, whatever the reason
+	batch := testBatchProofs(proofs, pubSignals)
+	batch[12].PubSignals = `["1"]`
+	batch[3].PubSignals = pubSignals[4]
+	batch[9].Proof = "{}"
+	batch[10].Proof = proofs[11]
+	err = vk.VerifyBatch(batch)
+	batchErr := &groth16.BatchVerificationError{}
+	c.Assert(err, qt.ErrorAs, &batchErr)
+	c.Assert(batchErr.Invalid, qt.DeepEquals, []int{3, 9, 10, 12})
+	c.Assert(batchErr.Errs[0], qt.ErrorMatches, "invalid proof")
+	c.Assert(batchErr.Errs[1], qt.ErrorMatches, "invalid pi_a: .*")
+	c.Assert(batchErr.Errs[2], qt.ErrorMatches, "invalid proof")
+	c.Assert(batchErr.Errs[3], qt.ErrorMatches, "invalid number of public signals: expected 2, got 1")
+	c.Assert(err, qt.ErrorMatches, "4 invalid proofs, proof 3: invalid proof")
+
+	// a batch of invalid proofs only
+	batch = testBatchProofs(proofs[:2], []string{pubSignals[1], pubSignals[0]})
+	c.Assert(vk.VerifyBatch(batch), qt.ErrorAs, &batchErr)
+	c.Assert(batchErr.Invalid, qt.DeepEquals, []int{0, 1})
+}
+
+func TestVerifyBatchBallotProof(t *testing.T) {
+	c := qt.New(t)
+
+	vkey, err := os.ReadFile(ballotProofVkeyFile)
+	c.Assert(err, qt.IsNil)
+	p, err := utils.NewProver(ballotProofWasmFile, ballotProofZkeyFile, 2)
+	c.Assert(err, qt.IsNil)
+	inputs := make([][]byte, 4)
+	for i := range inputs {
+		inputs[i] = testBallotProofInputs(c)
+	}
+	batch := []*groth16.Proof{}
+	for _, result := range p.ProveBatch(context.Background(), inputs, nil) {
+		c.Assert(result.Err, qt.IsNil)
+		batch = append(batch, &groth16.Proof{Proof: result.Proof, PubSignals: result.PubSignals})
+	}
+	c.Assert(groth16.VerifyBatch(vkey, batch), qt.IsNil)
+	batch[1].PubSignals, batch[2].PubSignals = batch[2].PubSignals, batch[1].PubSignals
+	batchErr := &groth16.BatchVerificationError{}
+	c.Assert(groth16.VerifyBatch(vkey, batch), qt.ErrorAs, &batchErr)
+	c.Assert(batchErr.Invalid, qt.DeepEquals, []int{1, 2})
+}
+
+func BenchmarkVerifyBatch(b *testing.B) {
+	c := qt.New(b)
+	// the ballot_proof_poseidon circuit has a single public signal
+	vkey, proofs, pubSignals := testGroth16Proofs(c, 1, 1000)
+	for _, n := range []int{10, 100, 1000} {
+		batch := testBatchProofs(proofs[:n], pubSignals[:n])
+		b.Run(fmt.Sprintf("VerifyProof/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, p := range batch {
+					c.Assert(utils.VerifyProof(p.Proof, p.PubSignals, vkey), qt.IsNil)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("VerifyBatch/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				c.Assert(groth16.VerifyBatch(vkey, batch), qt.IsNil)
+			}
+		})
+	}
+}
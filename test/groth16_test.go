@@ -0,0 +1,98 @@
+package test
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+	qt "github.com/frankban/quicktest"
+	"github.com/iden3/go-rapidsnark/witness/v2"
+	"github.com/iden3/go-rapidsnark/witness/wazero"
+	"github.com/vocdoni/z-ircuits/utils"
+	"github.com/vocdoni/z-ircuits/utils/groth16"
+)
+
+const ballotProofR1CSFile = "../artifacts/ballot_proof_test.r1cs"
+
+func TestGoBackendRootsOfUnity(t *testing.T) {
+	c := qt.New(t)
+
+	// snarkjs derives the roots of unity from the first quadratic non
+	// residue, 5, as 5^t where r - 1 = t * 2^28, so the domains and the odd
+	// cosets must be the same as the gnark-crypto ones
+	t28 := new(big.Int).Sub(fr.Modulus(), big.NewInt(1))
+	t28.Rsh(t28, 28)
+	root := new(big.Int).Exp(big.NewInt(5), t28, fr.Modulus())
+	for _, logN := range []uint{1, 10, 16, 28} {
+		expected := new(big.Int).Exp(root, new(big.Int).Lsh(big.NewInt(1), 28-logN), fr.Modulus())
+		generator, err := fft.Generator(1 << logN)
+		c.Assert(err, qt.IsNil)
+		c.Assert(generator.BigInt(new(big.Int)).Cmp(expected), qt.Equals, 0, qt.Commentf("n = 2^%d", logN))
+	}
+}
+
+func TestSnarkjsFilesErrors(t *testing.T) {
+	c := qt.New(t)
+
+	_, err := groth16.ReadZKey([]byte("wtns"))
+	c.Assert(err, qt.ErrorMatches, "invalid zkey file")
+	_, err = groth16.ReadR1CS([]byte("r1cs\x01\x00\x00\x00\x01\x00\x00\x00\x01\x00\x00\x00"))
+	c.Assert(err, qt.ErrorMatches, "invalid r1cs file: truncated section header")
+	_, err = groth16.ReadWitness([]byte("wtns\x02\x00\x00\x00\x00\x00\x00\x00"))
+	c.Assert(err, qt.ErrorMatches, "invalid witness field: missing section")
+	// the provers of the pure Go backend parse the zkey when created
+	_, err = utils.NewProverWithBackend([]byte("wasm"), []byte("wtns"), 1, &utils.GoBackend{})
+	c.Assert(err, qt.ErrorMatches, "invalid zkey file")
+}
+
+func TestGoBackend(t *testing.T) {
+	c := qt.New(t)
+
+	wasm, err := os.ReadFile(ballotProofWasmFile)
+	c.Assert(err, qt.IsNil)
+	zkey, err := os.ReadFile(ballotProofZkeyFile)
+	c.Assert(err, qt.IsNil)
+	vkey, err := os.ReadFile(ballotProofVkeyFile)
+	c.Assert(err, qt.IsNil)
+	bR1CS, err := os.ReadFile(ballotProofR1CSFile)
+	c.Assert(err, qt.IsNil)
+	r1cs, err := groth16.ReadR1CS(bR1CS)
+	c.Assert(err, qt.IsNil)
+	pk, err := groth16.ReadZKey(zkey)
+	c.Assert(err, qt.IsNil)
+	c.Assert(pk.NVars, qt.Equals, r1cs.NWires)
+	c.Assert(pk.NPublic, qt.Equals, r1cs.NPubOut+r1cs.NPubIn)
+
+	// the proofs of both backends verify with the same verification key, the
+	// default one is rapidsnark when building with cgo
+	backend := &utils.GoBackend{R1CS: r1cs}
+	bInputs := testBallotProofInputs(c)
+	for _, b := range []utils.ProverBackend{backend, utils.DefaultProverBackend} {
+		p, err := utils.NewProverWithBackend(wasm, zkey, 1, b)
+		c.Assert(err, qt.IsNil)
+		proofData, pubSignals, err := p.Prove(context.Background(), bInputs)
+		c.Assert(err, qt.IsNil)
+		c.Assert(utils.VerifyProof(proofData, pubSignals, vkey), qt.IsNil)
+	}
+
+	// a witness that does not satisfy the constraints is rejected
+	calc, err := witness.NewCalculator(wasm, witness.WithWasmEngine(wazero.NewCircom2WZWitnessCalculator))
+	c.Assert(err, qt.IsNil)
+	inputs, err := witness.ParseInputs(bInputs)
+	c.Assert(err, qt.IsNil)
+	wtns, err := calc.CalculateWTNSBin(inputs, true)
+	c.Assert(err, qt.IsNil)
+	w, err := groth16.ReadWitness(wtns)
+	c.Assert(err, qt.IsNil)
+	c.Assert(r1cs.CheckWitness(w), qt.IsNil)
+	// the last witness value is in the last 32 bytes of the file
+	wtns[len(wtns)-1] ^= 1
+	w, err = groth16.ReadWitness(wtns)
+	c.Assert(err, qt.IsNil)
+	c.Assert(r1cs.CheckWitness(w), qt.ErrorMatches, "constraint .* not satisfied")
+	_, _, err = backend.Prove(zkey, wtns)
+	c.Assert(err, qt.ErrorMatches, "constraint .* not satisfied")
+}
@@ -0,0 +1,245 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/z-ircuits/utils"
+	"github.com/vocdoni/z-ircuits/utils/groth16"
+)
+
+func g1Strings(p *bn254.G1Affine) []string {
+	return []string{p.X.String(), p.Y.String(), "1"}
+}
+
+func g2Strings(p *bn254.G2Affine) [][]string {
+	return [][]string{{p.X.A0.String(), p.X.A1.String()}, {p.Y.A0.String(), p.Y.A1.String()}, {"1", "0"}}
+}
+
+// testGroth16Proofs returns a random snarkjs verification key of nPublic
+// public signals and n valid proofs of it for random public signals. The
+// proofs are built from the trapdoor of the key instead of a circuit, so
+// they do not need the artifacts.
+func testGroth16Proofs(c *qt.C, nPublic, n int) ([]byte, []string, []string) {
+	_, _, g1, g2 := bn254.Generators()
+	random := func() fr.Element {
+		var e fr.Element
+		_, err := e.SetRandom()
+		c.Assert(err, qt.IsNil)
+		return e
+	}
+	mulG1 := func(e fr.Element) bn254.G1Affine {
+		var p bn254.G1Affine
+		return *p.ScalarMultiplication(&g1, e.BigInt(new(big.Int)))
+	}
+	mulG2 := func(e fr.Element) bn254.G2Affine {
+		var p bn254.G2Affine
+		return *p.ScalarMultiplication(&g2, e.BigInt(new(big.Int)))
+	}
+
+	alpha, beta, gamma, delta := random(), random(), random(), random()
+	ic := make([]fr.Element, nPublic+1)
+	icPoints := make([][]string, nPublic+1)
+	for i := range ic {
+		ic[i] = random()
+		p := mulG1(ic[i])
+		icPoints[i] = g1Strings(&p)
+	}
+	alpha1, beta2, gamma2, delta2 := mulG1(alpha), mulG2(beta), mulG2(gamma), mulG2(delta)
+	vkey, err := json.Marshal(map[string]any{
+		"protocol":   "groth16",
+		"curve":      "bn128",
+		"nPublic":    nPublic,
+		"vk_alpha_1": g1Strings(&alpha1),
+		"vk_beta_2":  g2Strings(&beta2),
+		"vk_gamma_2": g2Strings(&gamma2),
+		"vk_delta_2": g2Strings(&delta2),
+		"IC":         icPoints,
+	})
+	c.Assert(err, qt.IsNil)
+
+	// e(A, B) = e(alpha, beta) * e(vk_x, gamma) * e(C, delta) holds for
+	// a * b = alpha * beta + x * gamma + c * delta
+	var alphaBeta, deltaInv fr.Element
+	alphaBeta.Mul(&alpha, &beta)
+	deltaInv.Inverse(&delta)
+	proofs := make([]string, n)
+	pubSignals := make([]string, n)
+	for i := range proofs {
+		x := ic[0]
+		signals := make([]string, nPublic)
+		for j := range signals {
+			s := random()
+			signals[j] = s.String()
+			s.Mul(&s, &ic[j+1])
+			x.Add(&x, &s)
+		}
+		a, b := random(), random()
+		var cs fr.Element
+		cs.Mul(&a, &b)
+		cs.Sub(&cs, &alphaBeta)
+		x.Mul(&x, &gamma)
+		cs.Sub(&cs, &x)
+		cs.Mul(&cs, &deltaInv)
+		pa, pb, pc := mulG1(a), mulG2(b), mulG1(cs)
+		bProof, err := json.Marshal(utils.ProofData{
+			A: g1Strings(&pa), B: g2Strings(&pb), C: g1Strings(&pc), Protocol: "groth16", Curve: "bn128",
+		})
+		c.Assert(err, qt.IsNil)
+		bSignals, err := json.Marshal(signals)
+		c.Assert(err, qt.IsNil)
+		proofs[i], pubSignals[i] = string(bProof), string(bSignals)
+	}
+	return vkey, proofs, pubSignals
+}
+
+// goVerifyProof verifies the proof provided with the pure Go verifier,
+// parsing the verification key, to compare it with utils.VerifyProof, which
+// uses the go-rapidsnark verifier.
+func goVerifyProof(proofData, pubSignals string, vkey []byte) error {
+	vk, err := groth16.ParseVerificationKey(vkey)
+	if err != nil {
+		return err
+	}
+	return vk.Verify(proofData, pubSignals)
+}
+
+func TestVerificationKey(t *testing.T) {
+	c := qt.New(t)
+
+	vkey, proofs, pubSignals := testGroth16Proofs(c, 3, 2)
+	vk, err := groth16.ParseVerificationKey(vkey)
+	c.Assert(err, qt.IsNil)
+	c.Assert(vk.NPublic, qt.Equals, 3)
+	for i := range proofs {
+		c.Assert(vk.Verify(proofs[i], pubSignals[i]), qt.IsNil)
+		c.Assert(utils.VerifyProof(proofs[i], pubSignals[i], vkey), qt.IsNil)
+	}
+	// the proofs are bound to their public signals
+	c.Assert(vk.Verify(proofs[0], pubSignals[1]), qt.ErrorMatches, "invalid proof")
+	// the public signals must be canonical field elements
+	signals := []string{}
+	c.Assert(json.Unmarshal([]byte(pubSignals[0]), &signals), qt.IsNil)
+	value, _ := new(big.Int).SetString(signals[0], 10)
+	signals[0] = value.Add(value, fr.Modulus()).String()
+	bSignals, err := json.Marshal(signals)
+	c.Assert(err, qt.IsNil)
+	c.Assert(vk.Verify(proofs[0], string(bSignals)), qt.ErrorMatches, "invalid public signal 0: .*")
+	// and there must be nPublic of them
+	c.Assert(vk.Verify(proofs[0], `["1", "2"]`), qt.ErrorMatches,
+		"invalid number of public signals: expected 3, got 2")
+
+	// invalid verification keys
+	_, err = groth16.ParseVerificationKey([]byte(`{"protocol": "plonk"}`))
+	c.Assert(err, qt.ErrorMatches, "unsupported protocol plonk")
+	raw := map[string]any{}
+	c.Assert(json.Unmarshal(vkey, &raw), qt.IsNil)
+	raw["nPublic"] = 2
+	bVkey, err := json.Marshal(raw)
+	c.Assert(err, qt.IsNil)
+	_, err = groth16.ParseVerificationKey(bVkey)
+	c.Assert(err, qt.ErrorMatches, "invalid verification key: 4 IC points for 2 public signals")
+	raw["nPublic"] = 3
+	raw["vk_alpha_1"] = []string{"1", "3", "1"}
+	bVkey, err = json.Marshal(raw)
+	c.Assert(err, qt.IsNil)
+	_, err = groth16.ParseVerificationKey(bVkey)
+	c.Assert(err, qt.ErrorMatches, "invalid vk_alpha_1: point not in curve")
+}
+
+func TestVerificationKeyDifferential(t *testing.T) {
+	c := qt.New(t)
+
+	vkey, proofs, pubSignals := testGroth16Proofs(c, 2, 2)
+	otherVkey, _, _ := testGroth16Proofs(c, 2, 0)
+	vk, err := groth16.ParseVerificationKey(vkey)
+	c.Assert(err, qt.IsNil)
+	proof := utils.ProofData{}
+	c.Assert(json.Unmarshal([]byte(proofs[0]), &proof), qt.IsNil)
+	tampered := func(f func(p *utils.ProofData)) string {
+		p := proof
+		p.A, p.C = append([]string{}, proof.A...), append([]string{}, proof.C...)
+		f(&p)
+		b, err := json.Marshal(p)
+		c.Assert(err, qt.IsNil)
+		return string(b)
+	}
+	signals := []string{}
+	c.Assert(json.Unmarshal([]byte(pubSignals[0]), &signals), qt.IsNil)
+	value, _ := new(big.Int).SetString(signals[1], 10)
+	nonCanonical := fmt.Sprintf(`["%s", "%s"]`, signals[0], value.Add(value, fr.Modulus()))
+
+	for _, tc := range []struct {
+		name       string
+		vkey       []byte
+		proof      string
+		pubSignals string
+		valid      bool
+	}{
+		{"valid", vkey, proofs[0], pubSignals[0], true},
+		{"other valid", vkey, proofs[1], pubSignals[1], true},
+		{"other signals", vkey, proofs[0], pubSignals[1], false},
+		{"other vkey", otherVkey, proofs[0], pubSignals[0], false},
+		{"swapped points", vkey, tampered(func(p *utils.ProofData) { p.A, p.C = p.C, p.A }), pubSignals[0], false},
+		{"point not in curve", vkey, tampered(func(p *utils.ProofData) { p.C[1] = "1" }), pubSignals[0], false},
+		{"non canonical signal", vkey, proofs[0], nonCanonical, false},
+		{"missing signal", vkey, proofs[0], fmt.Sprintf(`["%s"]`, signals[0]), false},
+	} {
+		err := utils.VerifyProof(tc.proof, tc.pubSignals, tc.vkey)
+		c.Assert(err == nil, qt.Equals, tc.valid, qt.Commentf("%s: rapidsnark: %v", tc.name, err))
+		err = goVerifyProof(tc.proof, tc.pubSignals, tc.vkey)
+		c.Assert(err == nil, qt.Equals, tc.valid, qt.Commentf("%s: pure Go: %v", tc.name, err))
+		if string(tc.vkey) == string(vkey) {
+			err = vk.Verify(tc.proof, tc.pubSignals)
+			c.Assert(err == nil, qt.Equals, tc.valid, qt.Commentf("%s: VerificationKey: %v", tc.name, err))
+		}
+	}
+}
+
+func TestVerificationKeyBallotProof(t *testing.T) {
+	c := qt.New(t)
+
+	vkey, err := os.ReadFile(ballotProofVkeyFile)
+	c.Assert(err, qt.IsNil)
+	vk, err := groth16.ParseVerificationKey(vkey)
+	c.Assert(err, qt.IsNil)
+	proof, pubSignals, err := utils.CompileAndGenerateProof(testBallotProofInputs(c), ballotProofWasmFile, ballotProofZkeyFile)
+	c.Assert(err, qt.IsNil)
+	c.Assert(vk.Verify(proof, pubSignals), qt.IsNil)
+	c.Assert(utils.VerifyProof(proof, pubSignals, vkey), qt.IsNil)
+	signals := []string{}
+	c.Assert(json.Unmarshal([]byte(pubSignals), &signals), qt.IsNil)
+	signals[len(signals)-1] = "1"
+	bSignals, err := json.Marshal(signals)
+	c.Assert(err, qt.IsNil)
+	c.Assert(vk.Verify(proof, string(bSignals)), qt.ErrorMatches, "invalid proof")
+	c.Assert(utils.VerifyProof(proof, string(bSignals), vkey), qt.Not(qt.IsNil))
+}
+
+func BenchmarkVerifyProof(b *testing.B) {
+	c := qt.New(b)
+	vkey, proofs, pubSignals := testGroth16Proofs(c, 1, 1)
+	vk, err := groth16.ParseVerificationKey(vkey)
+	c.Assert(err, qt.IsNil)
+	b.Run("rapidsnark", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			c.Assert(utils.VerifyProof(proofs[0], pubSignals[0], vkey), qt.IsNil)
+		}
+	})
+	b.Run("ParseVerificationKey", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			c.Assert(goVerifyProof(proofs[0], pubSignals[0], vkey), qt.IsNil)
+		}
+	})
+	b.Run("VerificationKey", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			c.Assert(vk.Verify(proofs[0], pubSignals[0]), qt.IsNil)
+		}
+	})
+}
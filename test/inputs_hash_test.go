@@ -0,0 +1,117 @@
+package test
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/iden3/go-iden3-crypto/mimc7"
+	"github.com/vocdoni/z-ircuits/utils"
+)
+
+// circuitPreimageOrder parses the inputs hash preimage order of a hashed
+// inputs circuit, expanding the cipherfields loop for nFields fields.
+func circuitPreimageOrder(c *qt.C, circuit string, nFields int) []string {
+	content, err := os.ReadFile(circuit)
+	c.Assert(err, qt.IsNil)
+	re := regexp.MustCompile(`inputs_hasher\.in\[i\] <== ([^;]+);`)
+	order := []string{}
+	loop := []string{}
+	for _, match := range re.FindAllStringSubmatch(string(content), -1) {
+		signal := strings.TrimSpace(match[1])
+		if strings.HasPrefix(signal, "cipherfields[f]") {
+			loop = append(loop, signal)
+			continue
+		}
+		// the cipherfields loop ends before the next static input
+		for f := 0; f < nFields && len(loop) > 0; f++ {
+			for _, s := range loop {
+				order = append(order, strings.Replace(s, "[f]", fmt.Sprintf("[%d]", f), 1))
+			}
+		}
+		loop = nil
+		order = append(order, signal)
+	}
+	return order
+}
+
+func TestInputsHashOrder(t *testing.T) {
+	c := qt.New(t)
+
+	mode := &utils.BallotMode{MaxCount: 5, MaxValue: 16, MaxTotalCost: big.NewInt(1280), CostExp: 2}
+	process, voter := testProcessAndVoter(c, mode, big.NewInt(3))
+	inputs, err := utils.NewBallotProofInputs(utils.BallotProofPoseidon, 8,
+		[]*big.Int{big.NewInt(1), big.NewInt(2)}, process, voter)
+	c.Assert(err, qt.IsNil)
+	elements, err := utils.InputsHashDecomposition(process, voter, inputs.EncryptedBallot())
+	c.Assert(err, qt.IsNil)
+	names := []string{}
+	for i, element := range elements {
+		c.Assert(element.Index, qt.Equals, i)
+		names = append(names, element.Name)
+	}
+	// the order matches the one of every hashed inputs circuit
+	for _, circuit := range []string{
+		"../circuits/ballot_proof_mimc.circom",
+		"../circuits/ballot_proof_poseidon.circom",
+	} {
+		c.Assert(names, qt.DeepEquals, circuitPreimageOrder(c, circuit, 8), qt.Commentf("%s", circuit))
+	}
+	// and every element has the value of the circuit input with its name
+	circuitInputs := inputs.Map()
+	for _, element := range elements {
+		var value string
+		switch {
+		case strings.HasPrefix(element.Name, "pk["):
+			var i int
+			fmt.Sscanf(element.Name, "pk[%d]", &i)
+			value = circuitInputs["pk"].([]string)[i]
+		case strings.HasPrefix(element.Name, "cipherfields["):
+			var f, i, j int
+			fmt.Sscanf(element.Name, "cipherfields[%d][%d][%d]", &f, &i, &j)
+			value = circuitInputs["cipherfields"].([][][]string)[f][i][j]
+		default:
+			value = circuitInputs[element.Name].(string)
+		}
+		c.Assert(element.Value.String(), qt.Equals, value, qt.Commentf("%s", element.Name))
+	}
+}
+
+func TestInputsHashers(t *testing.T) {
+	c := qt.New(t)
+
+	mode := &utils.BallotMode{MaxCount: 3, MaxValue: 10, CostExp: 1}
+	process, voter := testProcessAndVoter(c, mode, nil)
+	fields := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)}
+	for _, variant := range []utils.BallotProofVariant{utils.BallotProofMiMC, utils.BallotProofPoseidon} {
+		inputs, err := utils.NewBallotProofInputs(variant, 8, fields, process, voter)
+		c.Assert(err, qt.IsNil)
+		hasher, err := utils.HasherForVariant(variant)
+		c.Assert(err, qt.IsNil)
+		hash, err := utils.InputsHash(hasher, process, voter, inputs.EncryptedBallot())
+		c.Assert(err, qt.IsNil)
+		c.Assert(hash.Cmp(inputs.InputsHash), qt.Equals, 0)
+		preimage, err := utils.InputsHashPreimage(process, voter, inputs.EncryptedBallot())
+		c.Assert(err, qt.IsNil)
+		c.Assert(preimage, qt.HasLen, 14+4*8)
+		switch variant {
+		case utils.BallotProofMiMC:
+			expected, err := mimc7.Hash(preimage, nil)
+			c.Assert(err, qt.IsNil)
+			c.Assert(hash.Cmp(expected), qt.Equals, 0)
+		case utils.BallotProofPoseidon:
+			expected, err := utils.MultiPoseidon(preimage...)
+			c.Assert(err, qt.IsNil)
+			c.Assert(hash.Cmp(expected), qt.Equals, 0)
+		}
+	}
+	hasher, err := utils.HasherForVariant(utils.BallotProofPlain)
+	c.Assert(err, qt.IsNil)
+	c.Assert(hasher, qt.IsNil)
+	_, err = utils.HasherForVariant("unknown")
+	c.Assert(err, qt.Not(qt.IsNil))
+}
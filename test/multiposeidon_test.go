@@ -0,0 +1,170 @@
+package test
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/iden3/go-iden3-crypto/poseidon"
+	"github.com/vocdoni/z-ircuits/utils"
+)
+
+// multiPoseidonTreeVectors are the MultiPoseidonTree hashes of the inputs
+// 1, 2, ..., n for every n, shared by the Go and circom implementations.
+var multiPoseidonTreeVectors = map[int]string{
+	1:    "18586133768512220936620570745912940619677854269274689475585506675881198879027",
+	16:   "9989051620750914585850546081941653841776809718687451684622678807385399211877",
+	17:   "8770585823063767024216894608354098830177643380596531891106165958687580947979",
+	256:  "13016222415369343325259815128341219454869416650015354579286108133977873877147",
+	257:  "19737451304052213258026287231919951348471878331705297616341921528847663520697",
+	300:  "9777912378941713492511122218791309444222917807031936240358338923829576152258",
+	4097: "11769274896019336851428089529853514250689695045933116722238604911592473332498",
+}
+
+// sequentialInputs returns the inputs 1, 2, ..., n.
+func sequentialInputs(n int) []*big.Int {
+	inputs := make([]*big.Int, n)
+	for i := range inputs {
+		inputs[i] = big.NewInt(int64(i + 1))
+	}
+	return inputs
+}
+
+func TestMultiPoseidonTree(t *testing.T) {
+	c := qt.New(t)
+
+	for n, expected := range multiPoseidonTreeVectors {
+		hash, err := utils.MultiPoseidonTree(sequentialInputs(n)...)
+		c.Assert(err, qt.IsNil)
+		c.Assert(hash.String(), qt.Equals, expected, qt.Commentf("n = %d", n))
+	}
+	_, err := utils.MultiPoseidonTree()
+	c.Assert(err, qt.ErrorMatches, "no inputs provided")
+
+	// the tree is the same as MultiPoseidon up to 256 inputs
+	for _, n := range []int{1, 2, 15, 16, 17, 32, 33, 100, 255, 256} {
+		inputs := sequentialInputs(n)
+		hash, err := utils.MultiPoseidonTree(inputs...)
+		c.Assert(err, qt.IsNil)
+		expected, err := utils.MultiPoseidon(inputs...)
+		c.Assert(err, qt.IsNil)
+		c.Assert(hash.Cmp(expected), qt.Equals, 0, qt.Commentf("n = %d", n))
+	}
+	_, err = utils.MultiPoseidon(sequentialInputs(257)...)
+	c.Assert(err, qt.ErrorMatches, "too many inputs")
+
+	// 300 inputs are hashed in 19 chunks (18 of 16 and one of 12), then the
+	// 19 chunk hashes in 2 chunks (16 and 3) and finally the 2 hashes
+	inputs := sequentialInputs(300)
+	chunkHashes := []*big.Int{}
+	for i := 0; i < 300; i += 16 {
+		hash, err := poseidon.Hash(inputs[i:min(i+16, 300)])
+		c.Assert(err, qt.IsNil)
+		chunkHashes = append(chunkHashes, hash)
+	}
+	c.Assert(chunkHashes, qt.HasLen, 19)
+	left, err := poseidon.Hash(chunkHashes[:16])
+	c.Assert(err, qt.IsNil)
+	right, err := poseidon.Hash(chunkHashes[16:])
+	c.Assert(err, qt.IsNil)
+	root, err := poseidon.Hash([]*big.Int{left, right})
+	c.Assert(err, qt.IsNil)
+	c.Assert(root.String(), qt.Equals, multiPoseidonTreeVectors[300])
+}
+
+func TestMultiPoseidonTreeCircuit(t *testing.T) {
+	c := qt.New(t)
+
+	var (
+		wasmFile = "../artifacts/multiposeidon_tree_test.wasm"
+		zkeyFile = "../artifacts/multiposeidon_tree_test_pkey.zkey"
+		vkeyFile = "../artifacts/multiposeidon_tree_test_vkey.json"
+	)
+	inputs := sequentialInputs(300)
+	bInputs, err := json.Marshal(map[string]any{
+		"in": utils.BigIntArrayToStringArray(inputs, len(inputs)),
+	})
+	c.Assert(err, qt.IsNil)
+	proofData, pubSignals, err := utils.CompileAndGenerateProof(bInputs, wasmFile, zkeyFile)
+	c.Assert(err, qt.IsNil)
+	vkey, err := os.ReadFile(vkeyFile)
+	c.Assert(err, qt.IsNil)
+	c.Assert(utils.VerifyProof(proofData, pubSignals, vkey), qt.IsNil)
+	// the only public signal is the output of the circuit
+	signals := []string{}
+	c.Assert(json.Unmarshal([]byte(pubSignals), &signals), qt.IsNil)
+	c.Assert(signals, qt.DeepEquals, []string{multiPoseidonTreeVectors[300]})
+}
+
+func TestMultiPoseidonHasher(t *testing.T) {
+	c := qt.New(t)
+
+	// every input count gives the same hash as MultiPoseidonTree, writing
+	// the inputs one by one and summing after every write
+	h := utils.NewMultiPoseidonHasher()
+	_, err := h.Sum()
+	c.Assert(err, qt.ErrorMatches, "no inputs provided")
+	inputs := sequentialInputs(300)
+	for i, input := range inputs {
+		c.Assert(h.Write(input), qt.IsNil)
+		c.Assert(h.Len(), qt.Equals, i+1)
+		hash, err := h.Sum()
+		c.Assert(err, qt.IsNil)
+		expected, err := utils.MultiPoseidonTree(inputs[:i+1]...)
+		c.Assert(err, qt.IsNil)
+		c.Assert(hash.Cmp(expected), qt.Equals, 0, qt.Commentf("n = %d", i+1))
+	}
+	for n, expected := range multiPoseidonTreeVectors {
+		h.Reset()
+		c.Assert(h.Write(sequentialInputs(n)...), qt.IsNil)
+		hash, err := h.Sum()
+		c.Assert(err, qt.IsNil)
+		c.Assert(hash.String(), qt.Equals, expected, qt.Commentf("n = %d", n))
+	}
+
+	// a snapshot is independent of the hasher it comes from
+	h.Reset()
+	c.Assert(h.Write(inputs[:20]...), qt.IsNil)
+	snapshot := h.Snapshot()
+	c.Assert(h.Write(inputs[20:]...), qt.IsNil)
+	c.Assert(snapshot.Len(), qt.Equals, 20)
+	c.Assert(snapshot.Write(inputs[20:]...), qt.IsNil)
+	hash, err := h.Sum()
+	c.Assert(err, qt.IsNil)
+	snapshotHash, err := snapshot.Sum()
+	c.Assert(err, qt.IsNil)
+	c.Assert(snapshotHash.String(), qt.Equals, multiPoseidonTreeVectors[300])
+	c.Assert(hash.String(), qt.Equals, multiPoseidonTreeVectors[300])
+	h.Restore(utils.NewMultiPoseidonHasher())
+	c.Assert(h.Len(), qt.Equals, 0)
+
+	// the inputs must be field elements
+	c.Assert(h.Write(big.NewInt(-1)), qt.ErrorMatches, ".*not inside the finite field")
+}
+
+func TestPoseidonInputsHash(t *testing.T) {
+	c := qt.New(t)
+
+	mode := &utils.BallotMode{
+		MaxCount:     5,
+		MaxValue:     16,
+		MaxTotalCost: big.NewInt(1280),
+		MinTotalCost: big.NewInt(5),
+		CostExp:      2,
+	}
+	process, voter := testProcessAndVoter(c, mode, big.NewInt(1))
+	processHasher, err := utils.NewProcessInputsHasher(process)
+	c.Assert(err, qt.IsNil)
+	for _, nFields := range []int{8, 64} {
+		fields := []*big.Int{big.NewInt(3), big.NewInt(5), big.NewInt(2), big.NewInt(4), big.NewInt(1)}
+		inputs, err := utils.NewBallotProofInputs(utils.BallotProofPoseidon, nFields, fields, process, voter)
+		c.Assert(err, qt.IsNil)
+		hash, err := utils.PoseidonInputsHash(processHasher, voter, inputs.EncryptedBallot())
+		c.Assert(err, qt.IsNil)
+		c.Assert(hash.Cmp(inputs.InputsHash), qt.Equals, 0, qt.Commentf("n_fields = %d", nFields))
+	}
+	// the process hasher is not modified
+	c.Assert(processHasher.Len(), qt.Equals, 11)
+}
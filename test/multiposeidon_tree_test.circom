@@ -0,0 +1,5 @@
+pragma circom 2.1.0;
+
+include "../circuits/lib/multiposeidon.circom";
+
+component main = MultiPoseidonTree(300);
@@ -0,0 +1,141 @@
+package test
+
+import (
+	"math/big"
+	"math/rand/v2"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/iden3/go-iden3-crypto/constants"
+	"github.com/vocdoni/z-ircuits/utils"
+)
+
+func TestInvalidBallots(t *testing.T) {
+	cases := []struct {
+		mode  *utils.BallotMode
+		rules []utils.BallotRule
+	}{
+		{
+			mode: &utils.BallotMode{
+				MaxCount:        5,
+				ForceUniqueness: true,
+				MaxValue:        16,
+				MinValue:        1,
+				MaxTotalCost:    big.NewInt(500),
+				MinTotalCost:    big.NewInt(100),
+				CostExp:         2,
+			},
+			rules: []utils.BallotRule{
+				utils.RuleUniqueness, utils.RuleMaxValue, utils.RuleMinValue,
+				utils.RuleMaxTotalCost, utils.RuleMinTotalCost, utils.RulePadding,
+			},
+		},
+		{
+			mode: &utils.BallotMode{
+				MaxCount:       8,
+				MaxValue:       10,
+				MaxTotalCost:   big.NewInt(1),
+				CostExp:        1,
+				CostFromWeight: true,
+			},
+			rules: []utils.BallotRule{utils.RuleMaxValue, utils.RuleMaxTotalCost},
+		},
+	}
+	weight := big.NewInt(40)
+	for i, tc := range cases {
+		c := qt.New(t)
+		gen, err := utils.NewBallotGenerator(tc.mode, 8, weight, rand.NewPCG(7, uint64(i)))
+		c.Assert(err, qt.IsNil)
+		fields, err := gen.Random()
+		c.Assert(err, qt.IsNil)
+		variants, err := utils.InvalidBallots(fields, tc.mode, weight)
+		c.Assert(err, qt.IsNil)
+		found := map[utils.BallotRule]bool{}
+		for _, variant := range variants {
+			found[variant.Rule] = true
+			err := utils.CheckBallot(variant.Fields, tc.mode, variant.Weight)
+			if !variant.Enforced {
+				c.Assert(err, qt.IsNil, qt.Commentf("%s", variant.Description))
+				continue
+			}
+			ballotErr := &utils.BallotError{}
+			c.Assert(err, qt.ErrorAs, &ballotErr, qt.Commentf("%s", variant.Description))
+			c.Assert(ballotErr.Rule, qt.Equals, variant.Rule, qt.Commentf("%s", variant.Description))
+		}
+		for _, rule := range tc.rules {
+			c.Assert(found[rule], qt.IsTrue, qt.Commentf("case %d: rule %s", i, rule))
+		}
+		// the original ballot is not modified
+		c.Assert(utils.CheckBallot(fields, tc.mode, weight), qt.IsNil)
+	}
+	// invalid ballots can not be mutated
+	_, err := utils.InvalidBallots([]*big.Int{big.NewInt(20)}, &utils.BallotMode{MaxCount: 1, MaxValue: 10, CostExp: 1}, nil)
+	qt.Assert(t, err, qt.Not(qt.IsNil))
+}
+
+func TestInvalidProofInputs(t *testing.T) {
+	c := qt.New(t)
+
+	mode := &utils.BallotMode{MaxCount: 2, MaxValue: 10, MaxTotalCost: big.NewInt(100), CostExp: 2}
+	privKey, pubKey := utils.GenerateKeyPair()
+	process, voter := testProcessAndVoter(c, mode, big.NewInt(1))
+	process.EncryptionKey = pubKey
+	var err error
+	process.EncryptionKeyProof, err = utils.ProveKeyKnowledge(privKey, process.ID.Bytes())
+	c.Assert(err, qt.IsNil)
+	fields := []*big.Int{big.NewInt(3), big.NewInt(5)}
+	inputs, err := utils.NewBallotProofInputs(utils.BallotProofPoseidon, 8, fields, process, voter)
+	c.Assert(err, qt.IsNil)
+	original := inputs.Map()
+	variants, err := utils.InvalidProofInputs(inputs)
+	c.Assert(err, qt.IsNil)
+	c.Assert(variants, qt.HasLen, 4)
+	for _, variant := range variants {
+		// the inputs hash is the one of the mutated inputs, so only the
+		// rule of the variant is broken
+		mutated := &utils.EncryptedBallot{
+			VoteID:       new(big.Int),
+			Cipherfields: variant.Inputs["cipherfields"].([][][]string),
+		}
+		mutated.VoteID.SetString(variant.Inputs["vote_id"].(string), 10)
+		inputsHash, err := utils.InputsHash(utils.PoseidonHasher{}, process, voter, mutated)
+		c.Assert(err, qt.IsNil)
+		switch variant.Rule {
+		case utils.RuleVoteID:
+			c.Assert(variant.Inputs["vote_id"], qt.Not(qt.Equals), inputs.VoteID.String())
+			c.Assert(variant.Inputs["inputs_hash"], qt.Equals, inputsHash.String())
+		case utils.RuleCipherfields:
+			// the tampered cipherfield decrypts to a different value
+			tampered, err := utils.CiphertextFromStrings(mutated.Cipherfields[0])
+			c.Assert(err, qt.IsNil)
+			msg, err := utils.Decrypt(privKey, tampered.C1, tampered.C2, 100)
+			c.Assert(err, qt.IsNil)
+			c.Assert(msg.Int64(), qt.Equals, int64(4))
+			c.Assert(variant.Inputs["inputs_hash"], qt.Equals, inputsHash.String())
+		case utils.RuleK:
+			c.Assert(variant.Inputs["k"], qt.Not(qt.Equals), inputs.K.String())
+			c.Assert(variant.Inputs["inputs_hash"], qt.Equals, inputsHash.String())
+		case utils.RuleInputsHash:
+			c.Assert(variant.Inputs["inputs_hash"], qt.Equals,
+				new(big.Int).Add(inputs.InputsHash, big.NewInt(1)).String())
+		default:
+			c.Fatalf("unexpected rule %s", variant.Rule)
+		}
+	}
+	// the original inputs are not modified
+	c.Assert(inputs.Map(), qt.DeepEquals, original)
+
+	// the wrong inputs hash is reduced to the field
+	inputs.InputsHash = new(big.Int).Sub(constants.Q, big.NewInt(1))
+	variants, err = utils.InvalidProofInputs(inputs)
+	c.Assert(err, qt.IsNil)
+	c.Assert(variants[3].Rule, qt.Equals, utils.RuleInputsHash)
+	c.Assert(variants[3].Inputs["inputs_hash"], qt.Equals, "0")
+
+	// the plain variant has no inputs hash to break
+	inputs, err = utils.NewBallotProofInputs(utils.BallotProofPlain, 8, fields, process, voter)
+	c.Assert(err, qt.IsNil)
+	variants, err = utils.InvalidProofInputs(inputs)
+	c.Assert(err, qt.IsNil)
+	c.Assert(variants, qt.HasLen, 3)
+}
@@ -0,0 +1,189 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/z-ircuits/utils"
+)
+
+const (
+	ballotProofWasmFile = "../artifacts/ballot_proof_test.wasm"
+	ballotProofZkeyFile = "../artifacts/ballot_proof_test_pkey.zkey"
+	ballotProofVkeyFile = "../artifacts/ballot_proof_test_vkey.json"
+)
+
+// testBallotProofInputs returns the JSON inputs of the ballot proof circuit
+// for a random valid ballot.
+func testBallotProofInputs(c *qt.C) []byte {
+	mode := &utils.BallotMode{
+		MaxCount:     5,
+		MaxValue:     16,
+		MaxTotalCost: big.NewInt(1280),
+		MinTotalCost: big.NewInt(5),
+		CostExp:      2,
+	}
+	process, voter := testProcessAndVoter(c, mode, big.NewInt(0))
+	fields := utils.GenerateBallotFields(5, 16, 0, false)
+	inputs, err := utils.NewBallotProofInputs(utils.BallotProofPlain, 8, fields, process, voter)
+	c.Assert(err, qt.IsNil)
+	bInputs, err := json.Marshal(inputs)
+	c.Assert(err, qt.IsNil)
+	return bInputs
+}
+
+func TestProver(t *testing.T) {
+	c := qt.New(t)
+
+	_, err := utils.NewProver("missing.wasm", ballotProofZkeyFile, 1)
+	c.Assert(err, qt.IsNotNil)
+	_, err = utils.NewProverFromBytes([]byte("wasm"), []byte{}, 1)
+	c.Assert(err, qt.ErrorMatches, "zkey is empty")
+
+	p, err := utils.NewProver(ballotProofWasmFile, ballotProofZkeyFile, 2)
+	c.Assert(err, qt.IsNil)
+	c.Assert(p.PoolSize(), qt.Equals, 2)
+	vkey, err := os.ReadFile(ballotProofVkeyFile)
+	c.Assert(err, qt.IsNil)
+
+	// more concurrent proofs than witness calculators
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		bInputs := testBallotProofInputs(c)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			proofData, pubSignals, err := p.Prove(context.Background(), bInputs)
+			c.Check(err, qt.IsNil)
+			c.Check(utils.VerifyProof(proofData, pubSignals, vkey), qt.IsNil)
+		}()
+	}
+	wg.Wait()
+
+	// a failed witness calculation does not exhaust the pool
+	for i := 0; i < 3; i++ {
+		_, _, err = p.Prove(context.Background(), []byte(`{"fields": ["1"]}`))
+		c.Assert(err, qt.IsNotNil)
+	}
+	_, _, err = p.Prove(context.Background(), testBallotProofInputs(c))
+	c.Assert(err, qt.IsNil)
+
+	// a cancelled context stops the proof
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = p.Prove(ctx, testBallotProofInputs(c))
+	c.Assert(err, qt.ErrorIs, context.Canceled)
+}
+
+func BenchmarkCompileAndGenerateProof(b *testing.B) {
+	bInputs := testBallotProofInputs(qt.New(b))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := utils.CompileAndGenerateProof(bInputs, ballotProofWasmFile, ballotProofZkeyFile); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkProver(b *testing.B) {
+	bInputs := testBallotProofInputs(qt.New(b))
+	p, err := utils.NewProver(ballotProofWasmFile, ballotProofZkeyFile, 1)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := p.Prove(context.Background(), bInputs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkProverParallel(b *testing.B) {
+	bInputs := testBallotProofInputs(qt.New(b))
+	p, err := utils.NewProver(ballotProofWasmFile, ballotProofZkeyFile, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, _, err := p.Prove(context.Background(), bInputs); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func TestProofPhases(t *testing.T) {
+	c := qt.New(t)
+
+	// errors are reported with their phase
+	_, _, timings, err := utils.CompileAndGenerateProofContext(context.Background(), []byte("{}"),
+		"missing.wasm", ballotProofZkeyFile)
+	phaseErr := &utils.PhaseError{}
+	c.Assert(errors.As(err, &phaseErr), qt.IsTrue)
+	c.Assert(phaseErr.Phase, qt.Equals, utils.ProofPhaseRead)
+	c.Assert(timings.Witness, qt.Equals, time.Duration(0))
+
+	bInputs := testBallotProofInputs(c)
+	_, _, timings, err = utils.CompileAndGenerateProofContext(context.Background(), bInputs,
+		ballotProofWasmFile, ballotProofZkeyFile)
+	c.Assert(err, qt.IsNil)
+	c.Assert(timings.Read > 0 && timings.Witness > 0 && timings.Prove > 0, qt.IsTrue)
+	c.Assert(timings.Total(), qt.Equals, timings.Read+timings.Witness+timings.Prove)
+
+	// a cancelled context does not start any phase
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, _, err = utils.CompileAndGenerateProofContext(ctx, bInputs, ballotProofWasmFile, ballotProofZkeyFile)
+	c.Assert(errors.As(err, &phaseErr), qt.IsTrue)
+	c.Assert(phaseErr.Phase, qt.Equals, utils.ProofPhaseRead)
+	c.Assert(err, qt.ErrorIs, context.Canceled)
+
+	// cancelling the context stops the proof generation in the phase in
+	// progress, without exhausting the pool of the prover
+	wasm, err := os.ReadFile(ballotProofWasmFile)
+	c.Assert(err, qt.IsNil)
+	zkey, err := os.ReadFile(ballotProofZkeyFile)
+	c.Assert(err, qt.IsNil)
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	backend := &blockingBackend{ctx: ctx, started: make(chan struct{})}
+	p, err := utils.NewProverWithBackend(wasm, zkey, 1, backend)
+	c.Assert(err, qt.IsNil)
+	for i := 0; i < 2; i++ {
+		proofCtx, cancelProof := context.WithCancel(ctx)
+		go func() {
+			<-backend.started
+			cancelProof()
+		}()
+		_, _, timings, err = p.ProveWithTimings(proofCtx, bInputs)
+		c.Assert(errors.As(err, &phaseErr), qt.IsTrue)
+		c.Assert(phaseErr.Phase, qt.Equals, utils.ProofPhaseProve)
+		c.Assert(err, qt.ErrorIs, context.Canceled)
+		c.Assert(timings.Witness > 0, qt.IsTrue)
+	}
+}
+
+// blockingBackend is a ProverBackend that blocks until its context is done,
+// to cancel the proofs in the prove phase deterministically. It notifies
+// every call through started.
+type blockingBackend struct {
+	ctx     context.Context
+	started chan struct{}
+}
+
+func (b *blockingBackend) Prove(_, _ []byte) (string, string, error) {
+	b.started <- struct{}{}
+	<-b.ctx.Done()
+	return "", "", b.ctx.Err()
+}
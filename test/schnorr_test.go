@@ -0,0 +1,45 @@
+package test
+
+import (
+	"math/big"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	"github.com/iden3/go-iden3-crypto/constants"
+	"github.com/vocdoni/z-ircuits/utils"
+	"go.vocdoni.io/dvote/util"
+)
+
+func TestKeyKnowledgeProof(t *testing.T) {
+	c := qt.New(t)
+
+	processID := util.RandomBytes(20)
+	privKey, pubKey := utils.GenerateKeyPair()
+	proof, err := utils.ProveKeyKnowledge(privKey, processID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(utils.VerifyKeyKnowledge(pubKey, processID, proof), qt.IsNil)
+	// the proof is bound to the process ID
+	c.Assert(utils.VerifyKeyKnowledge(pubKey, util.RandomBytes(20), proof), qt.Not(qt.IsNil))
+	// and to the public key
+	_, otherKey := utils.GenerateKeyPair()
+	c.Assert(utils.VerifyKeyKnowledge(otherKey, processID, proof), qt.Not(qt.IsNil))
+	// a tampered response is rejected
+	tampered := &utils.KeyProof{
+		Challenge: proof.Challenge,
+		Response:  new(big.Int).Add(proof.Response, big.NewInt(1)),
+	}
+	c.Assert(utils.VerifyKeyKnowledge(pubKey, processID, tampered), qt.Not(qt.IsNil))
+	// the response is not malleable: an unreduced one is rejected
+	malleated := &utils.KeyProof{
+		Challenge: proof.Challenge,
+		Response:  new(big.Int).Add(proof.Response, babyjub.SubOrder),
+	}
+	c.Assert(utils.VerifyKeyKnowledge(pubKey, processID, malleated), qt.ErrorMatches, "response out of range")
+	// a key out of the curve subgroup is rejected
+	lowOrder := &babyjub.PublicKey{X: big.NewInt(0), Y: new(big.Int).Sub(constants.Q, big.NewInt(1))}
+	c.Assert(utils.VerifyKeyKnowledge(lowOrder, processID, proof), qt.ErrorMatches, "public key not in curve subgroup")
+	// the identity point is not a valid key
+	identity := (*babyjub.PublicKey)(babyjub.NewPoint())
+	c.Assert(utils.VerifyKeyKnowledge(identity, processID, proof), qt.Not(qt.IsNil))
+}
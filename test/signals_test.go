@@ -0,0 +1,97 @@
+package test
+
+import (
+	"math/big"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/z-ircuits/utils"
+)
+
+func TestBallotProofSignals(t *testing.T) {
+	c := qt.New(t)
+
+	mode := &utils.BallotMode{
+		MaxCount:        3,
+		ForceUniqueness: true,
+		MaxValue:        16,
+		MaxTotalCost:    big.NewInt(1280),
+		MinTotalCost:    big.NewInt(3),
+		CostExp:         2,
+		CostFromWeight:  true,
+	}
+	fields := []*big.Int{big.NewInt(3), big.NewInt(5), big.NewInt(2)}
+	process, voter := testProcessAndVoter(c, mode, big.NewInt(1280))
+	inputs, err := utils.NewBallotProofInputs(utils.BallotProofPlain, 8, fields, process, voter)
+	c.Assert(err, qt.IsNil)
+
+	expected, err := utils.NewBallotProofSignals(process, voter, inputs.EncryptedBallot())
+	c.Assert(err, qt.IsNil)
+	encoded := expected.Encode()
+	c.Assert(encoded, qt.HasLen, 14+4*8)
+	// the ballot mode goes first, then the voter and process inputs
+	c.Assert(encoded[:8], qt.DeepEquals, utils.BigIntArrayToStringArray(mode.BigInts(), 8))
+	c.Assert(encoded[8], qt.Equals, voter.Address.String())
+	c.Assert(encoded[9], qt.Equals, voter.Weight.String())
+	c.Assert(encoded[10], qt.Equals, process.ID.String())
+	c.Assert(encoded[11], qt.Equals, inputs.VoteID.String())
+	c.Assert(encoded[12:14], qt.DeepEquals, []string{process.EncryptionKey.X.String(), process.EncryptionKey.Y.String()})
+	for f, cipherfield := range inputs.Cipherfields {
+		c.Assert(encoded[14+4*f:18+4*f], qt.DeepEquals, []string{
+			cipherfield[0][0], cipherfield[0][1], cipherfield[1][0], cipherfield[1][1],
+		})
+	}
+
+	pubSignals, err := expected.JSON()
+	c.Assert(err, qt.IsNil)
+	decoded, err := utils.DecodeBallotProofSignals(pubSignals, 8)
+	c.Assert(err, qt.IsNil)
+	c.Assert(decoded.Encode(), qt.DeepEquals, encoded)
+	c.Assert(decoded.BallotMode.ForceUniqueness, qt.IsTrue)
+	c.Assert(decoded.BallotMode.CostFromWeight, qt.IsTrue)
+	c.Assert(decoded.BallotMode.MaxTotalCost.Cmp(mode.MaxTotalCost), qt.Equals, 0)
+	c.Assert(decoded.EncryptionKey.X.Cmp(process.EncryptionKey.X), qt.Equals, 0)
+	for f, cipherfield := range decoded.Cipherfields {
+		if f < int(mode.MaxCount) {
+			c.Assert(cipherfield, qt.IsNotNil)
+		} else {
+			c.Assert(cipherfield, qt.IsNil)
+		}
+	}
+
+	// wrong number of signals, non boolean flags and non canonical values
+	_, err = utils.DecodeBallotProofSignals(pubSignals, 7)
+	c.Assert(err, qt.ErrorMatches, "invalid number of public signals.*")
+	_, err = utils.DecodeBallotProofSignals(`["1","2"`+pubSignals[len(`["3","1"`):], 8)
+	c.Assert(err, qt.ErrorMatches, "invalid force_uniqueness signal.*")
+	pubSignals, err = utils.EncodeInputsHashSignals(big.NewInt(0))
+	c.Assert(err, qt.IsNil)
+	c.Assert(pubSignals, qt.Equals, `["0"]`)
+	_, err = utils.DecodeInputsHashSignals(`["21888242871839275222246405745257275088548364400416034343698204186575808495617"]`)
+	c.Assert(err, qt.ErrorMatches, "invalid public signal 0.*")
+	_, err = utils.DecodeBallotCheckerSignals(`["1","1","0","2"]`, 4)
+	c.Assert(err, qt.ErrorMatches, "invalid mask\\[3\\] signal.*")
+}
+
+func TestBallotCipherSignals(t *testing.T) {
+	c := qt.New(t)
+
+	_, pubKey := utils.GenerateKeyPair()
+	k, err := utils.RandomK()
+	c.Assert(err, qt.IsNil)
+	msg := big.NewInt(42)
+	c1, c2 := utils.Encrypt(msg, pubKey, k)
+	signals := &utils.BallotCipherSignals{PublicKey: pubKey, Msg: msg, K: k, C1: c1, C2: c2}
+	encoded := signals.Encode()
+	c.Assert(encoded, qt.HasLen, 8)
+
+	pubSignals := `["` + encoded[0]
+	for _, s := range encoded[1:] {
+		pubSignals += `","` + s
+	}
+	pubSignals += `"]`
+	decoded, err := utils.DecodeBallotCipherSignals(pubSignals)
+	c.Assert(err, qt.IsNil)
+	c.Assert(decoded.Encode(), qt.DeepEquals, encoded)
+	c.Assert(decoded.C2.X.Cmp(c2.X), qt.Equals, 0)
+}
@@ -0,0 +1,114 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/z-ircuits/utils"
+	"github.com/vocdoni/z-ircuits/utils/groth16"
+)
+
+// verifyProofABI returns the ABI of the verifyProof function of the Solidity
+// verifiers of nPublic public signals.
+func verifyProofABI(c *qt.C, nPublic int) abi.ABI {
+	contractABI, err := abi.JSON(strings.NewReader(fmt.Sprintf(`[{
+		"type": "function", "name": "verifyProof", "stateMutability": "view",
+		"inputs": [
+			{"name": "_pA", "type": "uint256[2]"},
+			{"name": "_pB", "type": "uint256[2][2]"},
+			{"name": "_pC", "type": "uint256[2]"},
+			{"name": "_pubSignals", "type": "uint256[%d]"}
+		],
+		"outputs": [{"name": "", "type": "bool"}]
+	}]`, nPublic)))
+	c.Assert(err, qt.IsNil)
+	return contractABI
+}
+
+func TestSolidityVerifier(t *testing.T) {
+	c := qt.New(t)
+
+	vkey, _, _ := testGroth16Proofs(c, 3, 0)
+	raw := struct {
+		Alpha1 []string   `json:"vk_alpha_1"`
+		Beta2  [][]string `json:"vk_beta_2"`
+		Delta2 [][]string `json:"vk_delta_2"`
+		IC     [][]string `json:"IC"`
+	}{}
+	c.Assert(json.Unmarshal(vkey, &raw), qt.IsNil)
+
+	source, err := groth16.SolidityVerifier(vkey, "")
+	c.Assert(err, qt.IsNil)
+	c.Assert(source, qt.Contains, "contract Groth16Verifier {")
+	c.Assert(source, qt.Contains, "uint[3] calldata _pubSignals")
+	// the G2 coordinates are in the EIP-197 order, the imaginary part first
+	for _, constant := range []string{
+		"alphax = " + raw.Alpha1[0], "alphay = " + raw.Alpha1[1],
+		"betax1 = " + raw.Beta2[0][1], "betax2 = " + raw.Beta2[0][0],
+		"betay1 = " + raw.Beta2[1][1], "betay2 = " + raw.Beta2[1][0],
+		"deltax1 = " + raw.Delta2[0][1], "deltay2 = " + raw.Delta2[1][0],
+		"IC0x = " + raw.IC[0][0], "IC3y = " + raw.IC[3][1],
+	} {
+		c.Assert(source, qt.Contains, "uint256 constant "+constant+";")
+	}
+	// every public signal is checked and added to vk_x
+	for i := 0; i < 3; i++ {
+		c.Assert(source, qt.Contains, fmt.Sprintf("checkField(calldataload(add(_pubSignals, %d)))", i*32))
+		c.Assert(source, qt.Contains, fmt.Sprintf("g1_mulAccC(_pVk, IC%dx, IC%dy, calldataload(add(pubSignals, %d)))",
+			i+1, i+1, i*32))
+	}
+
+	source, err = groth16.SolidityVerifier(vkey, "BallotProofVerifier")
+	c.Assert(err, qt.IsNil)
+	c.Assert(source, qt.Contains, "contract BallotProofVerifier {")
+	_, err = groth16.SolidityVerifier(vkey, "Ballot Proof")
+	c.Assert(err, qt.ErrorMatches, `invalid contract name "Ballot Proof"`)
+	noSignalsVkey, _, _ := testGroth16Proofs(c, 0, 0)
+	_, err = groth16.SolidityVerifier(noSignalsVkey, "")
+	c.Assert(err, qt.ErrorMatches, "verification key without public signals")
+	_, err = groth16.SolidityVerifier([]byte(`{"protocol": "plonk"}`), "")
+	c.Assert(err, qt.ErrorMatches, "unsupported protocol plonk")
+}
+
+func TestSolidityCalldata(t *testing.T) {
+	c := qt.New(t)
+
+	_, proofs, pubSignals := testGroth16Proofs(c, 2, 1)
+	proof := utils.ProofData{}
+	c.Assert(json.Unmarshal([]byte(proofs[0]), &proof), qt.IsNil)
+	signals := []string{}
+	c.Assert(json.Unmarshal([]byte(pubSignals[0]), &signals), qt.IsNil)
+
+	calldata, err := groth16.NewSolidityCalldata(proofs[0], pubSignals[0])
+	c.Assert(err, qt.IsNil)
+	c.Assert(utils.BigIntArrayToStringArray(calldata.A[:], 2), qt.DeepEquals, proof.A[:2])
+	c.Assert(utils.BigIntArrayToStringArray(calldata.C[:], 2), qt.DeepEquals, proof.C[:2])
+	c.Assert(utils.BigIntArrayToStringArray(calldata.Input, 2), qt.DeepEquals, signals)
+	// the coordinates of B are swapped
+	c.Assert(utils.BigIntArrayToStringArray(calldata.B[0][:], 2), qt.DeepEquals, []string{proof.B[0][1], proof.B[0][0]})
+	c.Assert(utils.BigIntArrayToStringArray(calldata.B[1][:], 2), qt.DeepEquals, []string{proof.B[1][1], proof.B[1][0]})
+
+	// the encoding is the same as the go-ethereum one
+	input := [2]*big.Int{calldata.Input[0], calldata.Input[1]}
+	expected, err := verifyProofABI(c, 2).Pack("verifyProof", calldata.A, calldata.B, calldata.C, input)
+	c.Assert(err, qt.IsNil)
+	c.Assert(calldata.Pack(), qt.DeepEquals, expected)
+	c.Assert(calldata.Selector(), qt.DeepEquals, expected[:4])
+
+	// the snarkjs soliditycalldata format
+	hex := func(v *big.Int) string { return fmt.Sprintf(`"0x%064x"`, v) }
+	c.Assert(calldata.String(), qt.Equals, fmt.Sprintf("[%s,%s],[[%s,%s],[%s,%s]],[%s,%s],[%s,%s]",
+		hex(calldata.A[0]), hex(calldata.A[1]),
+		hex(calldata.B[0][0]), hex(calldata.B[0][1]), hex(calldata.B[1][0]), hex(calldata.B[1][1]),
+		hex(calldata.C[0]), hex(calldata.C[1]), hex(calldata.Input[0]), hex(calldata.Input[1])))
+
+	_, err = groth16.NewSolidityCalldata(proofs[0], `["1", "-1"]`)
+	c.Assert(err, qt.ErrorMatches, "invalid public signal 1: -1")
+	_, err = groth16.NewSolidityCalldata(`{"pi_a": ["1", "2"]}`, pubSignals[0])
+	c.Assert(err, qt.ErrorMatches, "invalid pi_a: expected 3 coordinates, got 2")
+}
@@ -0,0 +1,74 @@
+package test
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/iden3/go-iden3-crypto/constants"
+	"github.com/vocdoni/z-ircuits/utils"
+)
+
+func TestTally(t *testing.T) {
+	c := qt.New(t)
+
+	var (
+		nFields  = 8
+		maxCount = 5
+		maxValue = 16
+		nBallots = 10
+	)
+	privKey, pubKey := utils.GenerateKeyPair()
+	// accumulate half of the ballots in each of two partial tallies, one
+	// using the circuit format and the other using the plain format
+	expected := make([]int64, nFields)
+	partials := []*utils.Tally{utils.NewTally(nFields), utils.NewTally(nFields)}
+	for i := 0; i < nBallots; i++ {
+		fields := utils.GenerateBallotFields(maxCount, maxValue, 0, false)
+		for j, field := range fields {
+			expected[j] += field.Int64()
+		}
+		k, err := utils.RandomK()
+		c.Assert(err, qt.IsNil)
+		cipherfields, plainCipherfields := utils.CipherBallotFields(fields, nFields, pubKey, k)
+		if i%2 == 0 {
+			c.Assert(partials[0].Add(cipherfields), qt.IsNil)
+		} else {
+			c.Assert(partials[1].AddPlain(plainCipherfields), qt.IsNil)
+		}
+	}
+	tally := utils.NewTally(nFields)
+	for _, partial := range partials {
+		c.Assert(tally.Merge(partial), qt.IsNil)
+	}
+	c.Assert(tally.Count(), qt.Equals, uint64(nBallots))
+	// serialize and deserialize the tally before decrypting it
+	bTally, err := json.Marshal(tally)
+	c.Assert(err, qt.IsNil)
+	decoded := &utils.Tally{}
+	c.Assert(json.Unmarshal(bTally, decoded), qt.IsNil)
+	c.Assert(decoded.Count(), qt.Equals, uint64(nBallots))
+
+	results, err := decoded.Decrypt(privKey, uint64(maxValue*nBallots))
+	c.Assert(err, qt.IsNil)
+	for i, result := range results {
+		c.Assert(result.Cmp(big.NewInt(expected[i])), qt.Equals, 0, qt.Commentf("field %d", i))
+	}
+
+	// ballots with a different number of fields are rejected
+	cipherfields, _ := utils.CipherBallotFields(nil, nFields-1, pubKey, big.NewInt(1))
+	c.Assert(tally.Add(cipherfields), qt.Not(qt.IsNil))
+	c.Assert(tally.Merge(utils.NewTally(nFields+1)), qt.Not(qt.IsNil))
+	// and so are the ballots with points out of the curve subgroup, except
+	// the padding
+	cipherfields, plainCipherfields := utils.CipherBallotFields(nil, nFields, pubKey, big.NewInt(1))
+	lowOrderY := new(big.Int).Sub(constants.Q, big.NewInt(1))
+	cipherfields[0][0] = []string{"0", lowOrderY.String()}
+	c.Assert(tally.Add(cipherfields), qt.ErrorMatches, ".*cipherfield point not in curve subgroup")
+	plainCipherfields[0], plainCipherfields[1] = big.NewInt(0), lowOrderY
+	c.Assert(tally.AddPlain(plainCipherfields), qt.ErrorMatches, ".*cipherfield point not in curve subgroup")
+	padding, err := utils.CiphertextFromBigInts([]*big.Int{big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0)})
+	c.Assert(err, qt.IsNil)
+	c.Assert(padding.Strings(), qt.DeepEquals, utils.ZeroCiphertext().Strings())
+}
@@ -0,0 +1,170 @@
+package test
+
+import (
+	"math/big"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	"github.com/iden3/go-iden3-crypto/constants"
+	"github.com/vocdoni/z-ircuits/utils"
+)
+
+// setupTrustees simulates a t-of-n distributed key generation with n
+// in-process trustees and returns them with their published commitments.
+func setupTrustees(c *qt.C, threshold, n int) ([]*utils.Trustee, [][]*babyjub.Point) {
+	trustees := make([]*utils.Trustee, n)
+	commitments := make([][]*babyjub.Point, n)
+	for i := range trustees {
+		trustee, err := utils.NewTrustee(i+1, threshold, n)
+		c.Assert(err, qt.IsNil)
+		trustees[i] = trustee
+		commitments[i] = trustee.Commitments()
+	}
+	for _, from := range trustees {
+		for _, to := range trustees {
+			share, err := from.Share(to.Index)
+			c.Assert(err, qt.IsNil)
+			c.Assert(to.ReceiveShare(from.Index, share, commitments[from.Index-1]), qt.IsNil)
+		}
+	}
+	for _, trustee := range trustees {
+		c.Assert(trustee.Finalize(), qt.IsNil)
+	}
+	return trustees, commitments
+}
+
+func TestThresholdDecryption(t *testing.T) {
+	c := qt.New(t)
+
+	var (
+		threshold = 3
+		n         = 5
+		nFields   = 4
+		nBallots  = 5
+		maxValue  = 10
+	)
+	trustees, commitments := setupTrustees(c, threshold, n)
+	pubKey, err := utils.JointPublicKey(commitments)
+	c.Assert(err, qt.IsNil)
+	// the public shares match the secret shares of the trustees
+	for _, trustee := range trustees {
+		partial, err := trustee.PartialDecrypt(babyjub.B8)
+		c.Assert(err, qt.IsNil)
+		publicShare := utils.PublicShare(commitments, trustee.Index)
+		c.Assert(partial.Point.X.Cmp(publicShare.X), qt.Equals, 0)
+	}
+	// encrypt some ballots with the joint public key and tally them
+	expected := make([]int64, nFields)
+	tally := utils.NewTally(nFields)
+	for i := 0; i < nBallots; i++ {
+		fields := utils.GenerateBallotFields(nFields, maxValue, 0, false)
+		for j, field := range fields {
+			expected[j] += field.Int64()
+		}
+		k, err := utils.RandomK()
+		c.Assert(err, qt.IsNil)
+		cipherfields, _ := utils.CipherBallotFields(fields, nFields, pubKey, k)
+		c.Assert(tally.Add(cipherfields), qt.IsNil)
+	}
+	// decrypt every field with different subsets of trustees
+	subsets := [][]int{{0, 1, 2}, {2, 3, 4}, {4, 0, 2}, {0, 1, 2, 3, 4}}
+	for i, ciphertext := range tally.Ciphertexts() {
+		for _, subset := range subsets {
+			partials := []*utils.PartialDecryption{}
+			for _, idx := range subset {
+				partial, err := trustees[idx].PartialDecrypt(ciphertext.C1)
+				c.Assert(err, qt.IsNil)
+				c.Assert(utils.VerifyPartialDecryption(commitments, ciphertext.C1, partial), qt.IsNil)
+				partials = append(partials, partial)
+			}
+			result, err := utils.ThresholdDecrypt(commitments, ciphertext.C1, ciphertext.C2, partials,
+				uint64(maxValue*nBallots))
+			c.Assert(err, qt.IsNil)
+			c.Assert(result.Cmp(big.NewInt(expected[i])), qt.Equals, 0, qt.Commentf("field %d", i))
+		}
+		partials := make([]*utils.PartialDecryption, threshold)
+		for j := range partials {
+			partials[j], err = trustees[j].PartialDecrypt(ciphertext.C1)
+			c.Assert(err, qt.IsNil)
+		}
+		// a partial decryption can not be verified for another trustee or
+		// ciphertext
+		c.Assert(utils.VerifyPartialDecryption(commitments, babyjub.B8, partials[0]), qt.Not(qt.IsNil))
+		wrongIndex := *partials[0]
+		wrongIndex.Index = 4
+		c.Assert(utils.VerifyPartialDecryption(commitments, ciphertext.C1, &wrongIndex), qt.Not(qt.IsNil))
+		incomplete := *partials[1]
+		incomplete.Point = nil
+		c.Assert(utils.VerifyPartialDecryption(commitments, ciphertext.C1, &incomplete), qt.ErrorMatches,
+			"incomplete partial decryption")
+		// the invalid, duplicated and unknown partial decryptions are skipped
+		invalid := []*utils.PartialDecryption{nil, &wrongIndex, &incomplete, partials[1]}
+		for _, index := range []int{0, n + 1} {
+			unknown := *partials[1]
+			unknown.Index = index
+			invalid = append(invalid, &unknown)
+		}
+		result, err := utils.ThresholdDecrypt(commitments, ciphertext.C1, ciphertext.C2,
+			append(invalid, partials...), uint64(maxValue*nBallots))
+		c.Assert(err, qt.IsNil)
+		c.Assert(result.Cmp(big.NewInt(expected[i])), qt.Equals, 0, qt.Commentf("field %d", i))
+		// less than threshold valid partial decryptions are rejected
+		_, err = utils.CombinePartialDecryptions(commitments, ciphertext.C1, ciphertext.C2,
+			append(invalid, partials[1:]...))
+		c.Assert(err, qt.ErrorMatches, "not enough valid partial decryptions: expected 3, got 2")
+	}
+}
+
+func TestThresholdInvalidCommitments(t *testing.T) {
+	c := qt.New(t)
+
+	trustees, commitments := setupTrustees(c, 2, 3)
+	c1 := babyjub.NewPoint().Mul(big.NewInt(5), babyjub.B8)
+	partials := []*utils.PartialDecryption{}
+	for _, trustee := range trustees {
+		partial, err := trustee.PartialDecrypt(c1)
+		c.Assert(err, qt.IsNil)
+		partials = append(partials, partial)
+	}
+	_, err := utils.CombinePartialDecryptions(commitments, c1, c1, partials)
+	c.Assert(err, qt.IsNil)
+
+	_, err = utils.CombinePartialDecryptions(nil, c1, c1, partials)
+	c.Assert(err, qt.ErrorMatches, "no commitments provided")
+	// the threshold is the number of commitments of every trustee
+	short := append([][]*babyjub.Point{}, commitments...)
+	short[1] = short[1][:1]
+	_, err = utils.CombinePartialDecryptions(short, c1, c1, partials)
+	c.Assert(err, qt.ErrorMatches, "invalid number of commitments from trustee 2: expected 2, got 1")
+	// the commitments must be in the curve subgroup
+	lowOrder := &babyjub.Point{X: big.NewInt(0), Y: new(big.Int).Sub(constants.Q, big.NewInt(1))}
+	invalid := append([][]*babyjub.Point{}, commitments...)
+	invalid[2] = []*babyjub.Point{commitments[2][0], lowOrder}
+	_, err = utils.CombinePartialDecryptions(invalid, c1, c1, partials)
+	c.Assert(err, qt.ErrorMatches, "commitment of trustee 3 not in curve subgroup")
+}
+
+func TestThresholdInvalidShare(t *testing.T) {
+	c := qt.New(t)
+
+	dealer, err := utils.NewTrustee(1, 2, 3)
+	c.Assert(err, qt.IsNil)
+	receiver, err := utils.NewTrustee(2, 2, 3)
+	c.Assert(err, qt.IsNil)
+	share, err := dealer.Share(receiver.Index)
+	c.Assert(err, qt.IsNil)
+	// a tampered share does not match the dealer commitments
+	tampered := new(big.Int).Add(share, big.NewInt(1))
+	c.Assert(receiver.ReceiveShare(dealer.Index, tampered, dealer.Commitments()), qt.Not(qt.IsNil))
+	c.Assert(receiver.ReceiveShare(dealer.Index, share, dealer.Commitments()), qt.IsNil)
+	// the trustee can not be finalized without every share
+	c.Assert(receiver.Finalize(), qt.Not(qt.IsNil))
+	_, err = receiver.PartialDecrypt(babyjub.B8)
+	c.Assert(err, qt.Not(qt.IsNil))
+	// invalid configurations are rejected
+	_, err = utils.NewTrustee(1, 4, 3)
+	c.Assert(err, qt.Not(qt.IsNil))
+	_, err = utils.NewTrustee(0, 2, 3)
+	c.Assert(err, qt.Not(qt.IsNil))
+}
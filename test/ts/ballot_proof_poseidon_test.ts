@@ -1,4 +1,4 @@
-import { hexToField, multiHash, encrypt, prove, verify } from './utils';
+import { hexToField, multiHashTree, encrypt, prove, verify } from './utils';
 
 (async () => {
     const wasm = "../../artifacts/ballot_proof_poseidon_test.wasm";
@@ -57,7 +57,7 @@ import { hexToField, multiHash, encrypt, prove, verify } from './utils';
         plainBigCipherFields.push(cipherfields[i][1][0] as bigint);
         plainBigCipherFields.push(cipherfields[i][1][1] as bigint);
     }
-    inputs.inputs_hash = multiHash([
+    inputs.inputs_hash = multiHashTree([
         inputs.process_id,
         BigInt(inputs.max_count),
         BigInt(inputs.force_uniqueness),
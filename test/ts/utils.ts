@@ -63,6 +63,23 @@ export function multiHash(inputs: bigint[]): bigint {
     return poseidon.hash(hashes);
 }
 
+export function multiHashTree(inputs: bigint[]): bigint {
+    if (inputs.length === 0) {
+        throw new Error("no inputs provided");
+    }
+    let level = inputs;
+    for (;;) {
+        // hash every chunk of the current level
+        const hashes: bigint[] = [];
+        for (let i = 0; i < level.length; i += 16) {
+            hashes.push(poseidon.hash(level.slice(i, i + 16)));
+        }
+        // the root of the tree is the only hash of the last level
+        if (hashes.length === 1) return hashes[0];
+        level = hashes;
+    }
+}
+
 export async function prove(inputs: any, circuit_wasm: string, proving_key: string): Promise<[any, any]> {
     const { proof, publicSignals } = await snarkjs.groth16.fullProve(inputs, circuit_wasm, proving_key);
     return [proof, publicSignals];
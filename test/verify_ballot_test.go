@@ -0,0 +1,93 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/z-ircuits/utils"
+)
+
+func TestVerifyBallotSignals(t *testing.T) {
+	c := qt.New(t)
+
+	mode := &utils.BallotMode{
+		MaxCount:     5,
+		MaxValue:     16,
+		MaxTotalCost: big.NewInt(1280),
+		MinTotalCost: big.NewInt(5),
+		CostExp:      2,
+	}
+	fields := []*big.Int{big.NewInt(3), big.NewInt(5), big.NewInt(2), big.NewInt(4), big.NewInt(1)}
+	process, voter := testProcessAndVoter(c, mode, big.NewInt(1))
+	for _, variant := range []utils.BallotProofVariant{
+		utils.BallotProofPlain, utils.BallotProofMiMC, utils.BallotProofPoseidon,
+	} {
+		inputs, err := utils.NewBallotProofInputs(variant, 8, fields, process, voter)
+		c.Assert(err, qt.IsNil)
+		ballot := inputs.EncryptedBallot()
+		expected, err := utils.ExpectedPublicSignals(variant, process, voter, ballot)
+		c.Assert(err, qt.IsNil)
+		if variant == utils.BallotProofPlain {
+			c.Assert(expected, qt.HasLen, 14+4*8)
+			c.Assert(utils.BallotProofSignalNames(8), qt.HasLen, len(expected))
+		} else {
+			c.Assert(expected, qt.DeepEquals, []string{inputs.InputsHash.String()})
+		}
+		pubSignals, err := json.Marshal(expected)
+		c.Assert(err, qt.IsNil)
+		proof := &utils.BallotProof{Variant: variant, Proof: "{}", PubSignals: string(pubSignals)}
+
+		// the signals match, so the verification fails on the proof itself
+		err = utils.VerifyBallot([]byte("{}"), process, voter, ballot, proof)
+		c.Assert(err, qt.ErrorMatches, "invalid proof.*")
+
+		// a proof for another weight, address or process does not match
+		otherVoter := &utils.Voter{Address: voter.Address, Weight: big.NewInt(2)}
+		err = utils.VerifyBallot(nil, process, otherVoter, ballot, proof)
+		mismatch := &utils.SignalMismatchError{}
+		c.Assert(errors.As(err, &mismatch), qt.IsTrue)
+		otherProcess := *process
+		otherProcess.ID = new(big.Int).Add(process.ID, big.NewInt(1))
+		err = utils.VerifyBallot(nil, &otherProcess, voter, ballot, proof)
+		c.Assert(errors.As(err, &mismatch), qt.IsTrue)
+		if variant == utils.BallotProofPlain {
+			c.Assert(mismatch.Index, qt.Equals, 10)
+			c.Assert(mismatch.Name, qt.Equals, "process_id")
+			c.Assert(mismatch.Got, qt.Equals, process.ID.String())
+		} else {
+			c.Assert(mismatch.Name, qt.Equals, "inputs_hash")
+		}
+
+		// a proof for another encryption key does not match
+		_, otherKey := utils.GenerateKeyPair()
+		otherKeyProcess := *process
+		otherKeyProcess.EncryptionKey = otherKey
+		err = utils.VerifyBallot(nil, &otherKeyProcess, voter, ballot, proof)
+		c.Assert(errors.As(err, &mismatch), qt.IsTrue)
+		if variant == utils.BallotProofPlain {
+			c.Assert(mismatch.Name, qt.Equals, "pk[0]")
+		} else {
+			c.Assert(mismatch.Name, qt.Equals, "inputs_hash")
+		}
+
+		// a tampered cipherfield is reported by its position
+		tampered := &utils.EncryptedBallot{
+			VoteID:       ballot.VoteID,
+			Cipherfields: append([][][]string{}, inputs.Cipherfields...),
+		}
+		tampered.Cipherfields[0], tampered.Cipherfields[1] = tampered.Cipherfields[1], tampered.Cipherfields[0]
+		err = utils.VerifyBallot(nil, process, voter, tampered, proof)
+		c.Assert(errors.As(err, &mismatch), qt.IsTrue)
+		if variant == utils.BallotProofPlain {
+			c.Assert(mismatch.Name, qt.Equals, "cipherfields[0][0][0]")
+		}
+
+		// wrong number of public signals
+		proof.PubSignals = `["1","2"]`
+		err = utils.VerifyBallot(nil, process, voter, ballot, proof)
+		c.Assert(err, qt.ErrorMatches, "invalid number of public signals.*")
+	}
+}
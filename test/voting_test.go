@@ -0,0 +1,130 @@
+package test
+
+import (
+	"math/big"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/z-ircuits/utils"
+)
+
+func TestVotingSystems(t *testing.T) {
+	nFields := 8
+	singleChoice, err := utils.SingleChoice(5)
+	qt.Assert(t, err, qt.IsNil)
+	multipleChoice, err := utils.MultipleChoice(6, 1, 3)
+	qt.Assert(t, err, qt.IsNil)
+	approval, err := utils.Approval(4, 3)
+	qt.Assert(t, err, qt.IsNil)
+	rating, err := utils.Rating(3, 1, 10)
+	qt.Assert(t, err, qt.IsNil)
+	quadratic, err := utils.Quadratic(4, 100)
+	qt.Assert(t, err, qt.IsNil)
+	cumulative, err := utils.Cumulative(3, 10)
+	qt.Assert(t, err, qt.IsNil)
+
+	cases := []struct {
+		system  *utils.VotingSystem
+		valid   [][]uint64
+		invalid [][]uint64
+		results []int64
+	}{
+		{
+			system:  singleChoice,
+			valid:   [][]uint64{{0, 1, 0, 0, 0}, {0, 0, 0, 0, 1}, {0, 1, 0, 0, 0}},
+			invalid: [][]uint64{{0, 0, 0, 0, 0}, {1, 1, 0, 0, 0}, {2, 0, 0, 0, 0}},
+			results: []int64{0, 2, 0, 0, 1},
+		},
+		{
+			system:  multipleChoice,
+			valid:   [][]uint64{{1, 0, 0, 0, 0, 0}, {1, 1, 1, 0, 0, 0}, {0, 0, 1, 0, 0, 1}},
+			invalid: [][]uint64{{0, 0, 0, 0, 0, 0}, {1, 1, 1, 1, 0, 0}},
+			results: []int64{2, 1, 2, 0, 0, 1},
+		},
+		{
+			system:  approval,
+			valid:   [][]uint64{{0, 0, 0, 0}, {1, 0, 1, 1}, {1, 1, 0, 0}},
+			invalid: [][]uint64{{1, 1, 1, 1}, {0, 2, 0, 0}},
+			results: []int64{2, 1, 1, 1},
+		},
+		{
+			system:  rating,
+			valid:   [][]uint64{{1, 10, 5}, {3, 3, 3}},
+			invalid: [][]uint64{{0, 10, 5}, {1, 11, 5}},
+			results: []int64{4, 13, 8},
+		},
+		{
+			system:  quadratic,
+			valid:   [][]uint64{{10, 0, 0, 0}, {5, 5, 5, 5}, {0, 7, 7, 1}},
+			invalid: [][]uint64{{11, 0, 0, 0}, {5, 5, 5, 6}},
+			results: []int64{15, 12, 12, 6},
+		},
+		{
+			system:  cumulative,
+			valid:   [][]uint64{{10, 0, 0}, {2, 3, 5}, {0, 0, 1}},
+			invalid: [][]uint64{{11, 0, 0}, {5, 5, 1}},
+			results: []int64{12, 3, 6},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.system.Name, func(t *testing.T) {
+			c := qt.New(t)
+
+			privKey, pubKey := utils.GenerateKeyPair()
+			tally := utils.NewTally(nFields)
+			for _, votes := range tc.valid {
+				fields, err := tc.system.Ballot(votes, nFields)
+				c.Assert(err, qt.IsNil)
+				c.Assert(fields, qt.HasLen, nFields)
+				k, err := utils.RandomK()
+				c.Assert(err, qt.IsNil)
+				cipherfields, _ := utils.CipherBallotFields(fields, nFields, pubKey, k)
+				c.Assert(tally.Add(cipherfields), qt.IsNil)
+			}
+			for _, votes := range tc.invalid {
+				_, err := tc.system.Ballot(votes, nFields)
+				c.Assert(err, qt.ErrorAs, new(*utils.BallotError), qt.Commentf("votes %v", votes))
+			}
+			decrypted, err := tally.Decrypt(privKey, 1000)
+			c.Assert(err, qt.IsNil)
+			results, err := tc.system.Results(decrypted, tally.Count())
+			c.Assert(err, qt.IsNil)
+			c.Assert(results, qt.HasLen, tc.system.Options)
+			for i, result := range results {
+				c.Assert(result.Int64(), qt.Equals, tc.results[i], qt.Commentf("option %d", i))
+			}
+		})
+	}
+}
+
+func TestVotingSystemChoose(t *testing.T) {
+	c := qt.New(t)
+
+	system, err := utils.MultipleChoice(5, 2, 2)
+	c.Assert(err, qt.IsNil)
+	fields, err := system.Choose(8, 1, 3)
+	c.Assert(err, qt.IsNil)
+	c.Assert(utils.BigIntArrayToStringArray(fields, 8), qt.DeepEquals,
+		[]string{"0", "1", "0", "1", "0", "0", "0", "0"})
+	_, err = system.Choose(8, 1)
+	c.Assert(err, qt.Not(qt.IsNil))
+	_, err = system.Choose(8, 1, 1)
+	c.Assert(err, qt.Not(qt.IsNil))
+	_, err = system.Choose(8, 1, 5)
+	c.Assert(err, qt.Not(qt.IsNil))
+	// the options must fit in the circuit fields
+	_, err = system.Choose(4, 1, 3)
+	c.Assert(err, qt.Not(qt.IsNil))
+	// inconsistent results are rejected
+	_, err = system.Results([]*big.Int{big.NewInt(1), big.NewInt(1), big.NewInt(1), big.NewInt(0), big.NewInt(0)}, 1)
+	c.Assert(err, qt.Not(qt.IsNil))
+	_, err = system.Results([]*big.Int{big.NewInt(1), big.NewInt(1), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(1)}, 1)
+	c.Assert(err, qt.Not(qt.IsNil))
+	// invalid voting systems are rejected
+	_, err = utils.MultipleChoice(5, 3, 2)
+	c.Assert(err, qt.Not(qt.IsNil))
+	_, err = utils.Rating(3, 10, 1)
+	c.Assert(err, qt.Not(qt.IsNil))
+	_, err = utils.SingleChoice(0)
+	c.Assert(err, qt.Not(qt.IsNil))
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"encoding/json"
+
+	"github.com/vocdoni/z-ircuits/utils/groth16"
+)
+
+// ProverBackend generates Groth16 proofs from a snarkjs zkey and a witness
+// in the wtns format, returning the proof and the public signals in the
+// snarkjs JSON format, so they can be verified with VerifyProof.
+type ProverBackend interface {
+	Prove(zkey, witness []byte) (proof string, pubSignals string, err error)
+}
+
+// GoBackend is a pure Go ProverBackend that generates Groth16 proofs of the
+// BN254 curve from snarkjs zkeys, compatible with the snarkjs and
+// rapidsnark ones. It parses the zkey on every proof, except when it is used
+// by a Prover, which parses its zkey once.
+type GoBackend struct {
+	// R1CS is the optional constraint system of the circuit. If it is
+	// provided, the witness is checked against it before proving, so an
+	// invalid witness fails with the unsatisfied constraint instead of
+	// producing an invalid proof.
+	R1CS *groth16.R1CS
+
+	// pk is the parsed zkey of the backends created by withZKey, used
+	// instead of the zkey provided to Prove.
+	pk *groth16.ZKey
+}
+
+// Prove implements the ProverBackend interface.
+func (b *GoBackend) Prove(zkey, witness []byte) (string, string, error) {
+	pk := b.pk
+	if pk == nil {
+		var err error
+		if pk, err = groth16.ReadZKey(zkey); err != nil {
+			return "", "", err
+		}
+	}
+	w, err := groth16.ReadWitness(witness)
+	if err != nil {
+		return "", "", err
+	}
+	if b.R1CS != nil {
+		if err := b.R1CS.CheckWitness(w); err != nil {
+			return "", "", err
+		}
+	}
+	proof, pubSignals, err := pk.Prove(w)
+	if err != nil {
+		return "", "", err
+	}
+	bProof, err := json.Marshal(proof)
+	if err != nil {
+		return "", "", err
+	}
+	bPubSignals, err := json.Marshal(BigIntArrayToStringArray(pubSignals, len(pubSignals)))
+	if err != nil {
+		return "", "", err
+	}
+	return string(bProof), string(bPubSignals), nil
+}
+
+// withZKey returns a copy of the backend that generates the proofs with the
+// zkey provided, parsed once.
+func (b *GoBackend) withZKey(zkey []byte) (*GoBackend, error) {
+	pk, err := groth16.ReadZKey(zkey)
+	if err != nil {
+		return nil, err
+	}
+	return &GoBackend{R1CS: b.R1CS, pk: pk}, nil
+}
@@ -0,0 +1,19 @@
+//go:build cgo
+
+package utils
+
+import "github.com/iden3/go-rapidsnark/prover"
+
+// RapidsnarkBackend is the ProverBackend that uses rapidsnark through cgo.
+// It is only available when building with cgo.
+type RapidsnarkBackend struct{}
+
+// Prove implements the ProverBackend interface.
+func (RapidsnarkBackend) Prove(zkey, witness []byte) (string, string, error) {
+	return prover.Groth16ProverRaw(zkey, witness)
+}
+
+// DefaultProverBackend is the ProverBackend used by CompileAndGenerateProof
+// and by the provers created with NewProver. It is a RapidsnarkBackend when
+// building with cgo and a GoBackend otherwise.
+var DefaultProverBackend ProverBackend = RapidsnarkBackend{}
@@ -0,0 +1,8 @@
+//go:build !cgo
+
+package utils
+
+// DefaultProverBackend is the ProverBackend used by CompileAndGenerateProof
+// and by the provers created with NewProver. It is a RapidsnarkBackend when
+// building with cgo and a GoBackend otherwise.
+var DefaultProverBackend ProverBackend = &GoBackend{}
@@ -0,0 +1,241 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	"go.vocdoni.io/dvote/util"
+)
+
+// BallotProofVariant identifies a variant of the ballot proof circuit.
+type BallotProofVariant string
+
+const (
+	// BallotProofPlain is the ballot_proof.circom circuit, where every input
+	// but the fields and k is public.
+	BallotProofPlain BallotProofVariant = "ballot_proof"
+	// BallotProofMiMC is the ballot_proof_mimc.circom circuit, where the only
+	// public input is the MiMC7 hash of the inputs.
+	BallotProofMiMC BallotProofVariant = "ballot_proof_mimc"
+	// BallotProofPoseidon is the ballot_proof_poseidon.circom circuit, where
+	// the only public input is the MultiPoseidonTree hash of the inputs.
+	BallotProofPoseidon BallotProofVariant = "ballot_proof_poseidon"
+)
+
+// Process contains the parameters of a voting process used by the ballot
+// proof circuits.
+type Process struct {
+	// ID is the process ID as a field element.
+	ID            *big.Int
+	BallotMode    *BallotMode
+	EncryptionKey *babyjub.PublicKey
+	// EncryptionKeyProof is the proof of knowledge of the private key of the
+	// encryption key, verified before building any ballot. It is required
+	// unless SkipEncryptionKeyProof is set.
+	EncryptionKeyProof *KeyProof
+	// SkipEncryptionKeyProof allows a process without EncryptionKeyProof,
+	// like the ones whose encryption key is the JointPublicKey of the
+	// trustees, since no single party knows its private key.
+	SkipEncryptionKeyProof bool
+}
+
+// Voter contains the data of a voter used by the ballot proof circuits.
+type Voter struct {
+	// Address is the voter address as a field element.
+	Address *big.Int
+	Weight  *big.Int
+	// K is the voter secret used to derive the vote ID and the encryption
+	// randomness of every field.
+	K *big.Int
+}
+
+// BallotProofInputs contains the inputs of the ballot proof circuits for a
+// ballot, including the ones derived from the voter and process data: the
+// vote ID, the cipherfields and the inputs hash.
+type BallotProofInputs struct {
+	Variant      BallotProofVariant
+	NFields      int
+	Fields       []*big.Int
+	BallotMode   *BallotMode
+	Address      *big.Int
+	Weight       *big.Int
+	ProcessID    *big.Int
+	VoteID       *big.Int
+	PublicKey    *babyjub.PublicKey
+	K            *big.Int
+	Cipherfields [][][]string
+	// InputsHash is nil for the BallotProofPlain variant.
+	InputsHash *big.Int
+}
+
+// BytesToFF returns the field element of the big-endian bytes provided, like
+// the process ID or the voter address.
+func BytesToFF(b []byte) *big.Int {
+	return util.BigToFF(new(big.Int).SetBytes(b))
+}
+
+// NewBallotProofInputs returns the inputs of the ballot proof circuit
+// variant of nFields fields provided, for the ballot fields, the process and
+// the voter provided. It checks the process and the ballot before computing
+// the vote ID, the cipherfields and the inputs hash, so a misconfigured
+// process or an invalid ballot fails here instead of during the witness
+// calculation.
+func NewBallotProofInputs(variant BallotProofVariant, nFields int, fields []*big.Int,
+	process *Process, voter *Voter,
+) (*BallotProofInputs, error) {
+	if err := process.Validate(nFields); err != nil {
+		return nil, err
+	}
+	if len(fields) > nFields {
+		return nil, fmt.Errorf("too many fields: %d exceeds %d", len(fields), nFields)
+	}
+	if voter.Address == nil {
+		return nil, fmt.Errorf("missing voter address")
+	}
+	weight := voter.Weight
+	if weight == nil {
+		weight = big.NewInt(0)
+	}
+	inputs := &BallotProofInputs{
+		Variant:    variant,
+		NFields:    nFields,
+		Fields:     BigIntArrayToN(fields, nFields),
+		BallotMode: process.BallotMode,
+		Address:    util.BigToFF(voter.Address),
+		Weight:     weight,
+		ProcessID:  util.BigToFF(process.ID),
+		PublicKey:  process.EncryptionKey,
+		K:          voter.K,
+	}
+	if err := CheckBallot(inputs.Fields, inputs.BallotMode, inputs.Weight); err != nil {
+		return nil, err
+	}
+	if err := inputs.Recompute(); err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
+
+// Validate checks that the process can be used with a ballot proof circuit
+// of nFields fields: the ballot mode must be valid and the encryption key
+// must be a point of the curve subgroup, different from the identity, with
+// a valid proof of knowledge unless SkipEncryptionKeyProof is set.
+func (p *Process) Validate(nFields int) error {
+	if p.ID == nil {
+		return fmt.Errorf("missing process ID")
+	}
+	if p.BallotMode == nil {
+		return fmt.Errorf("missing ballot mode")
+	}
+	if err := p.BallotMode.Validate(nFields); err != nil {
+		return fmt.Errorf("invalid ballot mode: %v", err)
+	}
+	if p.EncryptionKey == nil {
+		return fmt.Errorf("missing encryption key")
+	}
+	pk := p.EncryptionKey.Point()
+	if !pk.InCurve() {
+		return fmt.Errorf("encryption key not in curve")
+	}
+	if !pk.InSubGroup() {
+		return fmt.Errorf("encryption key not in curve subgroup")
+	}
+	if pk.X.Sign() == 0 && pk.Y.Cmp(big.NewInt(1)) == 0 {
+		return fmt.Errorf("encryption key is the identity point")
+	}
+	if p.SkipEncryptionKeyProof {
+		return nil
+	}
+	if p.EncryptionKeyProof == nil {
+		return fmt.Errorf("missing encryption key proof")
+	}
+	if err := VerifyKeyKnowledge(p.EncryptionKey, p.ID.Bytes(), p.EncryptionKeyProof); err != nil {
+		return fmt.Errorf("invalid encryption key proof: %v", err)
+	}
+	return nil
+}
+
+// Recompute calculates the vote ID, the cipherfields and the inputs hash
+// from the rest of inputs, without checking the ballot. It allows to build
+// the inputs of invalid ballots for negative testing.
+func (b *BallotProofInputs) Recompute() error {
+	if b.K == nil {
+		return fmt.Errorf("missing k")
+	}
+	voteID, err := VoteID(b.ProcessID, b.Address, b.K)
+	if err != nil {
+		return err
+	}
+	b.VoteID = voteID
+	// only the fields considered by the max count are encrypted, the padding
+	// cipherfields are filled with zeros
+	considered := b.Fields
+	if b.BallotMode.MaxCount < uint64(len(considered)) {
+		considered = considered[:b.BallotMode.MaxCount]
+	}
+	b.Cipherfields, _ = CipherBallotFields(considered, b.NFields, b.PublicKey, b.K)
+	return b.recomputeInputsHash()
+}
+
+// recomputeInputsHash calculates the inputs hash from the rest of inputs,
+// or sets it to nil for the BallotProofPlain variant.
+func (b *BallotProofInputs) recomputeInputsHash() error {
+	hasher, err := HasherForVariant(b.Variant)
+	if err != nil {
+		return err
+	}
+	b.InputsHash = nil
+	if hasher != nil {
+		if b.InputsHash, err = InputsHash(hasher, b.Process(), b.Voter(), b.EncryptedBallot()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InputsHashPreimage returns the inputs hashed by the hashed inputs variants
+// in the circuit order. See InputsHashDecomposition.
+func (b *BallotProofInputs) InputsHashPreimage() ([]*big.Int, error) {
+	return InputsHashPreimage(b.Process(), b.Voter(), b.EncryptedBallot())
+}
+
+// Process returns the process data of the inputs.
+func (b *BallotProofInputs) Process() *Process {
+	return &Process{ID: b.ProcessID, BallotMode: b.BallotMode, EncryptionKey: b.PublicKey}
+}
+
+// Voter returns the voter data of the inputs.
+func (b *BallotProofInputs) Voter() *Voter {
+	return &Voter{Address: b.Address, Weight: b.Weight, K: b.K}
+}
+
+// EncryptedBallot returns the encrypted ballot of the inputs.
+func (b *BallotProofInputs) EncryptedBallot() *EncryptedBallot {
+	return &EncryptedBallot{VoteID: b.VoteID, Cipherfields: b.Cipherfields}
+}
+
+// Map returns the inputs in the format expected by the witness calculator
+// of the circuit variant.
+func (b *BallotProofInputs) Map() map[string]any {
+	inputs := b.BallotMode.CircuitInputs()
+	inputs["fields"] = BigIntArrayToStringArray(b.Fields, b.NFields)
+	inputs["address"] = b.Address.String()
+	inputs["weight"] = b.Weight.String()
+	inputs["process_id"] = b.ProcessID.String()
+	inputs["vote_id"] = b.VoteID.String()
+	inputs["pk"] = []string{b.PublicKey.X.String(), b.PublicKey.Y.String()}
+	inputs["k"] = b.K.String()
+	inputs["cipherfields"] = b.Cipherfields
+	if b.InputsHash != nil {
+		inputs["inputs_hash"] = b.InputsHash.String()
+	}
+	return inputs
+}
+
+// MarshalJSON encodes the inputs in the JSON format expected by
+// witness.ParseInputs.
+func (b *BallotProofInputs) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.Map())
+}
@@ -0,0 +1,182 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+)
+
+const (
+	// MaxCostBits is the number of bits of the comparators used by the
+	// BallotChecker circuit to check the total cost of a ballot, so every
+	// cost must be lower than 2^MaxCostBits.
+	MaxCostBits = 128
+	// MaxCostExpBits is the number of bits used by the SumPow template of the
+	// BallotChecker circuit to decompose the cost exponent.
+	MaxCostExpBits = 128
+)
+
+// BallotMode contains the parameters of a process that define a valid
+// ballot, checked by the BallotChecker circuit:
+//   - MaxCount: the number of fields of the ballot that are considered.
+//   - ForceUniqueness: if the values of the fields must be different.
+//   - MaxValue and MinValue: the inclusive bounds of the value of every field.
+//   - MaxTotalCost and MinTotalCost: the inclusive bounds of the total cost
+//     of the ballot, the sum of every field value to the power of CostExp. If
+//     MaxTotalCost is zero, the total cost is not bounded.
+//   - CostExp: the exponent used to calculate the cost of every field.
+//   - CostFromWeight: if the voter weight must be used as the maximum total
+//     cost instead of MaxTotalCost.
+type BallotMode struct {
+	MaxCount        uint64   `json:"max_count"`
+	ForceUniqueness bool     `json:"force_uniqueness"`
+	MaxValue        uint64   `json:"max_value"`
+	MinValue        uint64   `json:"min_value"`
+	MaxTotalCost    *big.Int `json:"max_total_cost"`
+	MinTotalCost    *big.Int `json:"min_total_cost"`
+	CostExp         uint64   `json:"cost_exp"`
+	CostFromWeight  bool     `json:"cost_from_weight"`
+}
+
+// Validate checks that the ballot mode is consistent and that it can be
+// used with a BallotChecker circuit of nFields fields, so at least one ballot
+// can satisfy it.
+func (m *BallotMode) Validate(nFields int) error {
+	if nFields <= 0 {
+		return fmt.Errorf("invalid number of fields: %d", nFields)
+	}
+	if m.MaxCount == 0 {
+		return fmt.Errorf("max count must be greater than zero")
+	}
+	if m.MaxCount > uint64(nFields) {
+		return fmt.Errorf("max count %d exceeds the number of fields %d", m.MaxCount, nFields)
+	}
+	if m.MaxValue > MaxMessage {
+		return fmt.Errorf("max value %d exceeds the limit of %d", m.MaxValue, MaxMessage)
+	}
+	if m.MinValue > m.MaxValue {
+		return fmt.Errorf("min value %d exceeds max value %d", m.MinValue, m.MaxValue)
+	}
+	if m.ForceUniqueness && m.MaxValue-m.MinValue+1 < m.MaxCount {
+		return fmt.Errorf("not enough values in [%d, %d] for %d unique fields", m.MinValue, m.MaxValue, m.MaxCount)
+	}
+	maxTotalCost, minTotalCost := m.maxTotalCost(), m.minTotalCost()
+	if maxTotalCost.Sign() < 0 || maxTotalCost.BitLen() > MaxCostBits {
+		return fmt.Errorf("max total cost %s out of the %d bits range", maxTotalCost, MaxCostBits)
+	}
+	if minTotalCost.Sign() < 0 || minTotalCost.BitLen() > MaxCostBits {
+		return fmt.Errorf("min total cost %s out of the %d bits range", minTotalCost, MaxCostBits)
+	}
+	// the greatest cost of a ballot must fit in the circuit comparators
+	highest, lowest := m.costRange()
+	if highest.BitLen() > MaxCostBits {
+		return fmt.Errorf("the highest total cost exceeds %d bits, reduce max value or cost exp", MaxCostBits)
+	}
+	// the cost bounds must be reachable by at least one ballot
+	if minTotalCost.Cmp(highest) > 0 {
+		return fmt.Errorf("min total cost %s exceeds the highest total cost %s", minTotalCost, highest)
+	}
+	if maxTotalCost.Sign() > 0 && !m.CostFromWeight {
+		if maxTotalCost.Cmp(lowest) < 0 {
+			return fmt.Errorf("max total cost %s is lower than the lowest total cost %s", maxTotalCost, lowest)
+		}
+		if minTotalCost.Cmp(maxTotalCost) > 0 {
+			return fmt.Errorf("min total cost %s exceeds max total cost %s", minTotalCost, maxTotalCost)
+		}
+	}
+	return nil
+}
+
+// costRange returns the highest and the lowest total cost of a ballot that
+// meets the value and uniqueness constraints of the ballot mode, ignoring its
+// cost bounds. The values of the ballot mode must be already checked by
+// Validate, so there are enough unique values.
+func (m *BallotMode) costRange() (*big.Int, *big.Int) {
+	highest, lowest := big.NewInt(0), big.NewInt(0)
+	for i := uint64(0); i < m.MaxCount; i++ {
+		high, low := m.MaxValue, m.MinValue
+		// if the values must be unique, the next ones are used
+		if m.ForceUniqueness {
+			high, low = m.MaxValue-i, m.MinValue+i
+		}
+		highest.Add(highest, m.Cost(high))
+		lowest.Add(lowest, m.Cost(low))
+		// avoid computing huge costs that would be rejected anyway
+		if highest.BitLen() > MaxCostBits {
+			break
+		}
+	}
+	return highest, lowest
+}
+
+// Cost returns the cost of a field value: value^CostExp. The result is capped
+// to a value of MaxCostBits+1 bits to avoid huge computations.
+func (m *BallotMode) Cost(value uint64) *big.Int {
+	if value > 1 && m.CostExp > MaxCostBits {
+		return new(big.Int).Lsh(big.NewInt(1), MaxCostBits)
+	}
+	return new(big.Int).Exp(new(big.Int).SetUint64(value), new(big.Int).SetUint64(m.CostExp), nil)
+}
+
+// CircuitInputs returns the ballot mode inputs of the circuits, encoded as
+// expected by the witness calculator.
+func (m *BallotMode) CircuitInputs() map[string]any {
+	inputs := map[string]any{}
+	keys := ballotModeKeys()
+	for i, value := range m.BigInts() {
+		inputs[keys[i]] = value.String()
+	}
+	return inputs
+}
+
+// BigInts returns the ballot mode as the preimage of the inputs hash, in the
+// same order used by the hashed inputs circuits: max_count,
+// force_uniqueness, max_value, min_value, max_total_cost, min_total_cost,
+// cost_exp and cost_from_weight.
+func (m *BallotMode) BigInts() []*big.Int {
+	return []*big.Int{
+		new(big.Int).SetUint64(m.MaxCount),
+		boolToBigInt(m.ForceUniqueness),
+		new(big.Int).SetUint64(m.MaxValue),
+		new(big.Int).SetUint64(m.MinValue),
+		m.maxTotalCost(),
+		m.minTotalCost(),
+		new(big.Int).SetUint64(m.CostExp),
+		boolToBigInt(m.CostFromWeight),
+	}
+}
+
+func (m *BallotMode) maxTotalCost() *big.Int {
+	if m.MaxTotalCost == nil {
+		return big.NewInt(0)
+	}
+	return new(big.Int).Set(m.MaxTotalCost)
+}
+
+func (m *BallotMode) minTotalCost() *big.Int {
+	if m.MinTotalCost == nil {
+		return big.NewInt(0)
+	}
+	return new(big.Int).Set(m.MinTotalCost)
+}
+
+// ballotModeKeys returns the names of the circuit signals of the ballot mode
+// in the same order as BallotMode.BigInts.
+func ballotModeKeys() []string {
+	return []string{
+		"max_count",
+		"force_uniqueness",
+		"max_value",
+		"min_value",
+		"max_total_cost",
+		"min_total_cost",
+		"cost_exp",
+		"cost_from_weight",
+	}
+}
+
+func boolToBigInt(b bool) *big.Int {
+	if b {
+		return big.NewInt(1)
+	}
+	return big.NewInt(0)
+}
@@ -0,0 +1,223 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/vocdoni/z-ircuits/utils/groth16"
+)
+
+// BatchOptions contains the options of the batch proving of a Prover.
+type BatchOptions struct {
+	// Workers is the number of proofs generated in parallel. If it is not
+	// positive, the pool size of the prover is used.
+	Workers int
+	// MaxPending is the maximum number of inputs read from the stream whose
+	// results have not been returned yet. When it is reached, no more
+	// inputs are read until the next result in order is consumed, so a slow
+	// consumer slows down the producer instead of accumulating results in
+	// memory. If it is not positive, twice the number of workers is used.
+	MaxPending int
+	// VerificationKey is the optional verification key of the circuit. If
+	// it is provided, it is parsed once and every proof is verified as it
+	// is generated.
+	VerificationKey []byte
+	// OnProgress is called every time a proof is completed, successfully or
+	// not, in completion order. The calls are not concurrent.
+	OnProgress func(progress BatchProgress, result *BatchResult)
+}
+
+// BatchProgress contains the progress of a batch proving.
+type BatchProgress struct {
+	// Submitted is the number of inputs read from the stream.
+	Submitted int
+	// Completed is the number of proofs completed, including the failed
+	// ones.
+	Completed int
+	// Failed is the number of proofs that failed.
+	Failed int
+}
+
+// BatchResult is the result of the proof of an input of a batch.
+type BatchResult struct {
+	// Index is the position of the input in the stream.
+	Index      int
+	Proof      string
+	PubSignals string
+	// Verified is true if the proof has been verified successfully.
+	Verified bool
+	Err      error
+}
+
+// batchJob is an input of a batch and the channel to send its result.
+type batchJob struct {
+	index  int
+	inputs []byte
+	result chan *BatchResult
+}
+
+// ProveStream generates the proofs of the JSON inputs received from the
+// channel provided in parallel, returning a channel that receives their
+// results in the same order as the inputs, each one with its own error. The
+// results channel is closed once the inputs channel is closed and every
+// result has been sent, or once the context is done. The results must be
+// consumed until the channel is closed, or the context cancelled, to release
+// the workers. See BatchOptions for the parallelism, backpressure,
+// verification and progress options.
+func (p *Prover) ProveStream(ctx context.Context, inputs <-chan []byte, opts *BatchOptions) <-chan *BatchResult {
+	if opts == nil {
+		opts = &BatchOptions{}
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = p.PoolSize()
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	maxPending := opts.MaxPending
+	if maxPending <= 0 {
+		maxPending = 2 * workers
+	}
+	var (
+		vk    *groth16.VerificationKey
+		vkErr error
+	)
+	if opts.VerificationKey != nil {
+		vk, vkErr = groth16.ParseVerificationKey(opts.VerificationKey)
+	}
+	var (
+		jobs    = make(chan *batchJob)
+		pending = make(chan *batchJob, maxPending)
+		// slots limits the inputs read whose results have not been received
+		// by the caller yet
+		slots    = make(chan struct{}, maxPending)
+		results  = make(chan *BatchResult)
+		progress = BatchProgress{}
+		mtx      sync.Mutex
+	)
+	// read the inputs while there is room for them, sending them to the
+	// workers and keeping their submission order
+	go func() {
+		defer close(jobs)
+		defer close(pending)
+		for index := 0; ; index++ {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			var job *batchJob
+			select {
+			case in, ok := <-inputs:
+				if !ok {
+					return
+				}
+				job = &batchJob{index: index, inputs: in, result: make(chan *BatchResult, 1)}
+			case <-ctx.Done():
+				return
+			}
+			// there is always room in pending, since it has a slot
+			pending <- job
+			mtx.Lock()
+			progress.Submitted++
+			mtx.Unlock()
+			select {
+			case jobs <- job:
+			case <-ctx.Done():
+				job.result <- &BatchResult{Index: job.index, Err: ctx.Err()}
+				return
+			}
+		}
+	}()
+	// generate the proofs
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range jobs {
+				result := p.proveBatchJob(ctx, job, vk, vkErr)
+				if opts.OnProgress != nil {
+					mtx.Lock()
+					progress.Completed++
+					if result.Err != nil {
+						progress.Failed++
+					}
+					opts.OnProgress(progress, result)
+					mtx.Unlock()
+				}
+				job.result <- result
+			}
+		}()
+	}
+	// send the results in submission order
+	go func() {
+		defer close(results)
+		for job := range pending {
+			var result *BatchResult
+			select {
+			case result = <-job.result:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case results <- result:
+				<-slots
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return results
+}
+
+// ProveBatch generates the proofs of the JSON inputs provided in parallel
+// and returns their results in the same order. It waits until every proof is
+// completed or the context is done, in which case the missing results
+// contain the context error. See ProveStream.
+func (p *Prover) ProveBatch(ctx context.Context, inputs [][]byte, opts *BatchOptions) []*BatchResult {
+	stream := make(chan []byte)
+	go func() {
+		defer close(stream)
+		for _, in := range inputs {
+			select {
+			case stream <- in:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	results := make([]*BatchResult, len(inputs))
+	for result := range p.ProveStream(ctx, stream, opts) {
+		results[result.Index] = result
+	}
+	for i := range results {
+		if results[i] == nil {
+			results[i] = &BatchResult{Index: i, Err: ctx.Err()}
+		}
+	}
+	return results
+}
+
+// proveBatchJob generates the proof of the job provided and verifies it if
+// a verification key is provided. If the verification key could not be
+// parsed, vkErr is returned without generating the proof.
+func (p *Prover) proveBatchJob(ctx context.Context, job *batchJob, vk *groth16.VerificationKey,
+	vkErr error,
+) *BatchResult {
+	result := &BatchResult{Index: job.index}
+	if vkErr != nil {
+		result.Err = vkErr
+		return result
+	}
+	result.Proof, result.PubSignals, result.Err = p.Prove(ctx, job.inputs)
+	if result.Err != nil || vk == nil {
+		return result
+	}
+	if err := vk.Verify(result.Proof, result.PubSignals); err != nil {
+		result.Err = fmt.Errorf("invalid proof: %v", err)
+		return result
+	}
+	result.Verified = true
+	return result
+}
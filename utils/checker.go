@@ -0,0 +1,215 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/iden3/go-iden3-crypto/constants"
+	"go.vocdoni.io/dvote/util"
+)
+
+// BallotRule identifies a rule of the BallotChecker circuit that a ballot
+// must meet to be valid.
+type BallotRule string
+
+const (
+	// RuleMaxCount: the max count can not exceed the number of fields.
+	RuleMaxCount BallotRule = "max_count"
+	// RuleFieldSize: every field must be a valid input for the circuit
+	// comparators.
+	RuleFieldSize BallotRule = "field_size"
+	// RuleUniqueness: the considered fields must be unique if
+	// force_uniqueness is set.
+	RuleUniqueness BallotRule = "force_uniqueness"
+	// RuleMaxValue: the considered fields can not exceed max_value.
+	RuleMaxValue BallotRule = "max_value"
+	// RuleMinValue: the considered fields can not be lower than min_value.
+	RuleMinValue BallotRule = "min_value"
+	// RuleCostSize: the total cost must fit in the circuit comparators with
+	// its bounds.
+	RuleCostSize BallotRule = "cost_size"
+	// RuleMaxTotalCostSize: max_total_cost must fit in the circuit
+	// comparators.
+	RuleMaxTotalCostSize BallotRule = "max_total_cost_size"
+	// RuleMinTotalCostSize: min_total_cost must fit in the circuit
+	// comparators.
+	RuleMinTotalCostSize BallotRule = "min_total_cost_size"
+	// RuleWeightSize: the weight must fit in the circuit comparator with the
+	// total cost if cost_from_weight is set.
+	RuleWeightSize BallotRule = "weight_size"
+	// RuleMaxTotalCost: the total cost can not exceed max_total_cost, or the
+	// weight if cost_from_weight is set, if max_total_cost is not zero.
+	RuleMaxTotalCost BallotRule = "max_total_cost"
+	// RuleMinTotalCost: the total cost can not be lower than min_total_cost.
+	RuleMinTotalCost BallotRule = "min_total_cost"
+)
+
+// BallotError is returned by CheckBallot when a ballot does not meet a rule
+// of the BallotChecker circuit. It includes the rule broken and the details
+// of the rejection.
+type BallotError struct {
+	Rule BallotRule
+	// Field is the index of the field that breaks the rule, if any, or -1.
+	Field int
+	// Other is the index of the field duplicated by Field, if any, or -1.
+	Other int
+	// Value is the value that breaks the rule: the field value, the total
+	// cost of the ballot or the weight.
+	Value *big.Int
+	// Bound is the limit exceeded by Value, if any, or the value compared
+	// with it for the comparator size rules.
+	Bound *big.Int
+}
+
+func (e *BallotError) Error() string {
+	switch e.Rule {
+	case RuleMaxCount:
+		return fmt.Sprintf("max count %s exceeds the number of fields %s", e.Value, e.Bound)
+	case RuleFieldSize:
+		return fmt.Sprintf("field %d value %s exceeds %s bits", e.Field, e.Value, e.Bound)
+	case RuleUniqueness:
+		return fmt.Sprintf("field %d duplicates field %d", e.Field, e.Other)
+	case RuleMaxValue:
+		return fmt.Sprintf("field %d value %s exceeds max value %s", e.Field, e.Value, e.Bound)
+	case RuleMinValue:
+		return fmt.Sprintf("field %d value %s is lower than min value %s", e.Field, e.Value, e.Bound)
+	case RuleCostSize:
+		return fmt.Sprintf("total cost %s out of the comparator range of %s", e.Value, e.Bound)
+	case RuleMaxTotalCostSize:
+		return fmt.Sprintf("max total cost %s out of the %s bits comparator range", e.Value, e.Bound)
+	case RuleMinTotalCostSize:
+		return fmt.Sprintf("min total cost %s out of the %s bits comparator range", e.Value, e.Bound)
+	case RuleWeightSize:
+		return fmt.Sprintf("weight %s out of the comparator range of total cost %s", e.Value, e.Bound)
+	case RuleMaxTotalCost:
+		return fmt.Sprintf("total cost %s exceeds %s", e.Value, e.Bound)
+	case RuleMinTotalCost:
+		return fmt.Sprintf("total cost %s is lower than %s", e.Value, e.Bound)
+	default:
+		return fmt.Sprintf("ballot breaks rule %s", e.Rule)
+	}
+}
+
+// CheckBallot checks the ballot fields provided against the ballot mode and
+// the voter weight, mirroring the BallotChecker circuit:
+//  1. The fields considered are the first max_count ones (MaskGenerator).
+//  2. If force_uniqueness is set, the considered fields must be different
+//     (UniqueArray).
+//  3. The considered fields must be between min_value and max_value
+//     (ArrayInBounds).
+//  4. The total cost is the sum of the considered fields to the power of
+//     cost_exp, computed in the circuit field (SumPow).
+//  5. If max_total_cost is not zero, the total cost can not exceed it, or the
+//     weight if cost_from_weight is set (Mux).
+//  6. The total cost can not be lower than min_total_cost.
+//
+// The comparators of steps 5 and 6 (GreaterThan and LessEqThan of 128 bits)
+// are checked like the circuit does, so their inputs must be in range even if
+// max_total_cost is zero.
+//
+// The fields must include the padding up to the number of fields of the
+// circuit. It returns a *BallotError with the first rule broken, if any.
+func CheckBallot(fields []*big.Int, mode *BallotMode, weight *big.Int) error {
+	if weight == nil {
+		weight = big.NewInt(0)
+	}
+	// 1. generate the mask of the considered fields
+	if mode.MaxCount > uint64(len(fields)) {
+		return &BallotError{
+			Rule:  RuleMaxCount,
+			Field: -1,
+			Other: -1,
+			Value: new(big.Int).SetUint64(mode.MaxCount),
+			Bound: big.NewInt(int64(len(fields))),
+		}
+	}
+	for i, field := range fields {
+		if field.Sign() < 0 || field.BitLen() > 252 {
+			return &BallotError{Rule: RuleFieldSize, Field: i, Other: -1, Value: field, Bound: big.NewInt(252)}
+		}
+	}
+	considered := fields[:mode.MaxCount]
+	// 2. check the uniqueness of the fields
+	if mode.ForceUniqueness {
+		for j := range considered {
+			for i := 0; i < j; i++ {
+				if considered[i].Cmp(considered[j]) == 0 {
+					return &BallotError{Rule: RuleUniqueness, Field: j, Other: i, Value: considered[j]}
+				}
+			}
+		}
+	}
+	// 3. check the bounds of the fields
+	maxValue := new(big.Int).SetUint64(mode.MaxValue)
+	minValue := new(big.Int).SetUint64(mode.MinValue)
+	for i, field := range considered {
+		if field.Cmp(maxValue) > 0 {
+			return &BallotError{Rule: RuleMaxValue, Field: i, Other: -1, Value: field, Bound: maxValue}
+		}
+		if field.Cmp(minValue) < 0 {
+			return &BallotError{Rule: RuleMinValue, Field: i, Other: -1, Value: field, Bound: minValue}
+		}
+	}
+	// 4. compute the total cost
+	totalCost := TotalCost(considered, mode.CostExp)
+	// 5. check the max total cost if it is not zero, using the weight if
+	// cost_from_weight is set
+	maxTotalCost := util.BigToFF(mode.maxTotalCost())
+	hasMax, ok := lessThan(big.NewInt(0), maxTotalCost)
+	if !ok {
+		return &BallotError{Rule: RuleMaxTotalCostSize, Field: -1, Other: -1, Value: maxTotalCost, Bound: big.NewInt(MaxCostBits)}
+	}
+	bound := maxTotalCost
+	if mode.CostFromWeight {
+		bound = util.BigToFF(weight)
+	}
+	// LessEqThan(total, bound) is LessThan(total, bound + 1)
+	withinMax, ok := lessThan(totalCost, new(big.Int).Add(bound, big.NewInt(1)))
+	switch {
+	case !ok && mode.CostFromWeight:
+		return &BallotError{Rule: RuleWeightSize, Field: -1, Other: -1, Value: bound, Bound: totalCost}
+	case !ok && bound.BitLen() > MaxCostBits:
+		return &BallotError{Rule: RuleMaxTotalCostSize, Field: -1, Other: -1, Value: bound, Bound: big.NewInt(MaxCostBits)}
+	case !ok:
+		return &BallotError{Rule: RuleCostSize, Field: -1, Other: -1, Value: totalCost, Bound: bound}
+	case hasMax && !withinMax:
+		return &BallotError{Rule: RuleMaxTotalCost, Field: -1, Other: -1, Value: totalCost, Bound: bound}
+	}
+	// 6. check the min total cost: GreaterThan(total + 1, min) is
+	// LessThan(min, total + 1)
+	minTotalCost := util.BigToFF(mode.minTotalCost())
+	aboveMin, ok := lessThan(minTotalCost, new(big.Int).Add(totalCost, big.NewInt(1)))
+	switch {
+	case !ok && minTotalCost.BitLen() > MaxCostBits:
+		return &BallotError{Rule: RuleMinTotalCostSize, Field: -1, Other: -1, Value: minTotalCost, Bound: big.NewInt(MaxCostBits)}
+	case !ok:
+		return &BallotError{Rule: RuleCostSize, Field: -1, Other: -1, Value: totalCost, Bound: minTotalCost}
+	case !aboveMin:
+		return &BallotError{Rule: RuleMinTotalCost, Field: -1, Other: -1, Value: totalCost, Bound: minTotalCost}
+	}
+	return nil
+}
+
+// TotalCost returns the sum of the fields provided to the power of costExp,
+// computed in the circuit field like the SumPow circuit template.
+func TotalCost(fields []*big.Int, costExp uint64) *big.Int {
+	exp := new(big.Int).SetUint64(costExp)
+	total := big.NewInt(0)
+	for _, field := range fields {
+		total.Add(total, new(big.Int).Exp(field, exp, constants.Q))
+	}
+	return total.Mod(total, constants.Q)
+}
+
+// lessThan mirrors the circomlib LessThan(MaxCostBits) template: it returns
+// if a < b, and false in ok if the Num2Bits(MaxCostBits+1) decomposition of
+// a + 2^MaxCostBits - b, computed in the circuit field, is not satisfiable,
+// so the circuit rejects the inputs.
+func lessThan(a, b *big.Int) (lt, ok bool) {
+	v := new(big.Int).Lsh(big.NewInt(1), MaxCostBits)
+	v.Add(v, a).Sub(v, b).Mod(v, constants.Q)
+	if v.BitLen() > MaxCostBits+1 {
+		return false, false
+	}
+	return v.Bit(MaxCostBits) == 0, true
+}
@@ -1,11 +1,39 @@
 package utils
 
 import (
+	"fmt"
 	"math/big"
+	"slices"
+	"sync"
 
 	"github.com/iden3/go-iden3-crypto/babyjub"
+	"github.com/iden3/go-iden3-crypto/constants"
 )
 
+// MaxMessage is the biggest message that can be encrypted by the ElGamal
+// circuit, which encodes the message using 32 bits.
+const MaxMessage = uint64(1)<<32 - 1
+
+// maxBabyStepsTables is the number of baby steps tables kept in the cache,
+// for the last table sizes used.
+const maxBabyStepsTables = 4
+
+// babySteps caches the baby steps tables of the discrete log solver for the
+// last sizes used, to avoid recomputing them on every decryption. The sizes
+// are sorted from the least to the most recently used.
+var babySteps = struct {
+	sync.Mutex
+	tables map[uint64]*babyStepsEntry
+	sizes  []uint64
+}{tables: map[uint64]*babyStepsEntry{}}
+
+// babyStepsEntry is a baby steps table of the cache, built once by the first
+// caller that needs it.
+type babyStepsEntry struct {
+	once  sync.Once
+	table map[[32]byte]uint64
+}
+
 func GenerateKeyPair() (babyjub.PrivateKey, *babyjub.PublicKey) {
 	privkey := babyjub.NewRandPrivKey()
 	return privkey, privkey.Public()
@@ -22,3 +50,221 @@ func Encrypt(message *big.Int, publicKey *babyjub.PublicKey, k *big.Int) (*babyj
 	c2p := babyjub.NewPointProjective().Add(m.Projective(), s.Projective())
 	return c1, c2p.Affine()
 }
+
+// Rerandomize returns a fresh encryption of the message encrypted in the
+// ciphertext (c1, c2), adding to it an encryption of zero with the random r:
+// (c1 + [r] * G, c2 + [r] * publicKey).
+func Rerandomize(publicKey *babyjub.PublicKey, c1, c2 *babyjub.Point, r *big.Int) (*babyjub.Point, *babyjub.Point) {
+	z1, z2 := Encrypt(big.NewInt(0), publicKey, r)
+	return addPoints(c1, z1), addPoints(c2, z2)
+}
+
+// RerandomizeWithProof rerandomizes the ciphertext (c1, c2) like Rerandomize
+// and also returns a proof that the new ciphertext encrypts the same message,
+// which is a DLEQ proof of the knowledge of r such that
+// c1' - c1 = [r] * G and c2' - c2 = [r] * publicKey.
+func RerandomizeWithProof(publicKey *babyjub.PublicKey, c1, c2 *babyjub.Point, r *big.Int) (
+	*babyjub.Point, *babyjub.Point, *DLEQProof, error,
+) {
+	rc1, rc2 := Rerandomize(publicKey, c1, c2, r)
+	proof, err := ProveDLEQ(r, babyjub.B8, addPoints(rc1, negPoint(c1)),
+		publicKey.Point(), addPoints(rc2, negPoint(c2)))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return rc1, rc2, proof, nil
+}
+
+// VerifyRerandomization checks the proof that the ciphertext (rc1, rc2) is a
+// rerandomization of the ciphertext (c1, c2) under the public key provided.
+func VerifyRerandomization(publicKey *babyjub.PublicKey, c1, c2, rc1, rc2 *babyjub.Point, proof *DLEQProof) error {
+	return VerifyDLEQ(proof, babyjub.B8, addPoints(rc1, negPoint(c1)),
+		publicKey.Point(), addPoints(rc2, negPoint(c2)))
+}
+
+// DecryptPoint returns the point that encodes the message encrypted in the
+// ciphertext (c1, c2), that is [message] * G.
+func DecryptPoint(privKey babyjub.PrivateKey, c1, c2 *babyjub.Point) *babyjub.Point {
+	// s = [sk] * c1
+	s := babyjub.NewPoint().Mul(privKey.Scalar().BigInt(), c1)
+	// m = c2 - s
+	return addPoints(c2, negPoint(s))
+}
+
+// Decrypt decrypts the ciphertext (c1, c2) with the private key provided and
+// recovers the message from its point encoding solving the discrete log. The
+// message must be lower or equal than max, which can not exceed MaxMessage.
+func Decrypt(privKey babyjub.PrivateKey, c1, c2 *babyjub.Point, max uint64) (*big.Int, error) {
+	return DiscreteLog(DecryptPoint(privKey, c1, c2), max)
+}
+
+// DecryptWithProof decrypts the ciphertext (c1, c2) like Decrypt and also
+// returns a Chaum-Pedersen proof of the correct decryption, which is a DLEQ
+// proof of the knowledge of sk such that publicKey = [sk] * G and
+// c2 - [message] * G = [sk] * c1.
+func DecryptWithProof(privKey babyjub.PrivateKey, c1, c2 *babyjub.Point, max uint64) (*big.Int, *DLEQProof, error) {
+	m := DecryptPoint(privKey, c1, c2)
+	message, err := DiscreteLog(m, max)
+	if err != nil {
+		return nil, nil, err
+	}
+	proof, err := ProveDLEQ(privKey.Scalar().BigInt(), babyjub.B8, privKey.Public().Point(),
+		c1, addPoints(c2, negPoint(m)))
+	if err != nil {
+		return nil, nil, err
+	}
+	return message, proof, nil
+}
+
+// VerifyDecryption checks the proof that the message provided is the correct
+// decryption of the ciphertext (c1, c2) with the private key of the public
+// key provided.
+func VerifyDecryption(publicKey *babyjub.PublicKey, c1, c2 *babyjub.Point, message *big.Int, proof *DLEQProof) error {
+	m := babyjub.NewPoint().Mul(message, babyjub.B8)
+	return VerifyDLEQ(proof, babyjub.B8, publicKey.Point(), c1, addPoints(c2, negPoint(m)))
+}
+
+// DiscreteLog returns the scalar m such that [m] * G = point, looking for it
+// in the range [0, max] using the baby-step giant-step algorithm. The max
+// value can not exceed MaxMessage.
+func DiscreteLog(point *babyjub.Point, max uint64) (*big.Int, error) {
+	if max > MaxMessage {
+		return nil, fmt.Errorf("max message %d exceeds the limit of %d", max, MaxMessage)
+	}
+	// the table size is the ceil of the square root of the range size
+	size := uint64(1)
+	for size*size < max+1 {
+		size++
+	}
+	table := babyStepsTable(size)
+	// giant step: -[size] * G
+	giant := negPoint(babyjub.NewPoint().Mul(new(big.Int).SetUint64(size), babyjub.B8)).Projective()
+	current := point.Projective()
+	for i := uint64(0); i < size; i++ {
+		if j, ok := table[current.Affine().Compress()]; ok {
+			m := i*size + j
+			if m > max {
+				break
+			}
+			return new(big.Int).SetUint64(m), nil
+		}
+		current = current.Add(current, giant)
+	}
+	return nil, fmt.Errorf("message not found in range [0, %d]", max)
+}
+
+// babyStepsTable returns the table that maps the points [j] * G to j for
+// every j lower than size. The table returned must not be modified.
+func babyStepsTable(size uint64) map[[32]byte]uint64 {
+	babySteps.Lock()
+	entry, ok := babySteps.tables[size]
+	if !ok {
+		entry = &babyStepsEntry{}
+		babySteps.tables[size] = entry
+	}
+	babySteps.sizes = append(slices.DeleteFunc(babySteps.sizes, func(s uint64) bool { return s == size }), size)
+	if len(babySteps.sizes) > maxBabyStepsTables {
+		delete(babySteps.tables, babySteps.sizes[0])
+		babySteps.sizes = babySteps.sizes[1:]
+	}
+	babySteps.Unlock()
+	// the table is built out of the lock, so only the callers of the same
+	// size wait for it
+	entry.once.Do(func() {
+		entry.table = make(map[[32]byte]uint64, size)
+		current := babyjub.NewPointProjective()
+		base := babyjub.B8.Projective()
+		for j := uint64(0); j < size; j++ {
+			entry.table[current.Affine().Compress()] = j
+			current = current.Add(current, base)
+		}
+	})
+	return entry.table
+}
+
+// addPoints returns the sum of the points a and b.
+func addPoints(a, b *babyjub.Point) *babyjub.Point {
+	return babyjub.NewPointProjective().Add(a.Projective(), b.Projective()).Affine()
+}
+
+// negPoint returns the opposite of the point p, which is (-x, y) in a
+// twisted Edwards curve.
+func negPoint(p *babyjub.Point) *babyjub.Point {
+	x := new(big.Int).Neg(p.X)
+	x.Mod(x, constants.Q)
+	return &babyjub.Point{X: x, Y: new(big.Int).Set(p.Y)}
+}
+
+// Ciphertext is an ElGamal ciphertext composed by the points (C1, C2).
+type Ciphertext struct {
+	C1 *babyjub.Point
+	C2 *babyjub.Point
+}
+
+// ZeroCiphertext returns the neutral element of the ciphertexts addition,
+// where both points are the identity.
+func ZeroCiphertext() *Ciphertext {
+	return &Ciphertext{C1: babyjub.NewPoint(), C2: babyjub.NewPoint()}
+}
+
+// Add returns the component-wise sum of the ciphertexts c and o, which
+// encrypts the sum of their messages.
+func (c *Ciphertext) Add(o *Ciphertext) *Ciphertext {
+	return &Ciphertext{
+		C1: addPoints(c.C1, o.C1),
+		C2: addPoints(c.C2, o.C2),
+	}
+}
+
+// Strings returns the ciphertext in the format of a circuit cipherfield.
+func (c *Ciphertext) Strings() [][]string {
+	return [][]string{
+		{c.C1.X.String(), c.C1.Y.String()},
+		{c.C2.X.String(), c.C2.Y.String()},
+	}
+}
+
+// CiphertextFromStrings parses a cipherfield in the circuit format. The
+// padding cipherfields, filled with zeros, are parsed as the zero ciphertext.
+func CiphertextFromStrings(cipherfield [][]string) (*Ciphertext, error) {
+	if len(cipherfield) != 2 || len(cipherfield[0]) != 2 || len(cipherfield[1]) != 2 {
+		return nil, fmt.Errorf("invalid cipherfield format")
+	}
+	coords := make([]*big.Int, 4)
+	for i, s := range []string{cipherfield[0][0], cipherfield[0][1], cipherfield[1][0], cipherfield[1][1]} {
+		coord, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid cipherfield coordinate: %s", s)
+		}
+		coords[i] = coord
+	}
+	return CiphertextFromBigInts(coords)
+}
+
+// CiphertextFromBigInts parses a cipherfield in the plain format (c1.X, c1.Y,
+// c2.X, c2.Y), checking that its points are in the curve subgroup. The
+// padding cipherfields, filled with zeros, are parsed as the zero ciphertext.
+func CiphertextFromBigInts(coords []*big.Int) (*Ciphertext, error) {
+	if len(coords) != 4 {
+		return nil, fmt.Errorf("invalid number of cipherfield coordinates: %d", len(coords))
+	}
+	isPadding := true
+	for _, coord := range coords {
+		if coord.Sign() != 0 {
+			isPadding = false
+			break
+		}
+	}
+	if isPadding {
+		return ZeroCiphertext(), nil
+	}
+	c := &Ciphertext{
+		C1: &babyjub.Point{X: new(big.Int).Set(coords[0]), Y: new(big.Int).Set(coords[1])},
+		C2: &babyjub.Point{X: new(big.Int).Set(coords[2]), Y: new(big.Int).Set(coords[3])},
+	}
+	// the points out of the subgroup would corrupt the tallies
+	if !c.C1.InSubGroup() || !c.C2.InSubGroup() {
+		return nil, fmt.Errorf("cipherfield point not in curve subgroup")
+	}
+	return c, nil
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/iden3/go-iden3-crypto/babyjub"
+)
+
+// DLEQProof is a non-interactive Chaum-Pedersen proof of the equality of
+// discrete logs, that proves the knowledge of a scalar x such that
+// H1 = [x] * G1 and H2 = [x] * G2 without revealing it. The proof is made
+// non-interactive using the Fiat-Shamir heuristic with the Poseidon hash.
+type DLEQProof struct {
+	Challenge *big.Int `json:"challenge"`
+	Response  *big.Int `json:"response"`
+}
+
+// dleqDomain is the first input of the challenges of the DLEQ proofs, to
+// separate them from the ones of other proofs that use the same hash.
+var dleqDomain = new(big.Int).SetBytes([]byte("z-ircuits/dleq"))
+
+// ProveDLEQ generates a proof of the knowledge of x such that h1 = [x] * g1
+// and h2 = [x] * g2.
+func ProveDLEQ(x *big.Int, g1, h1, g2, h2 *babyjub.Point) (*DLEQProof, error) {
+	w, err := RandomK()
+	if err != nil {
+		return nil, err
+	}
+	// a1 = [w] * g1, a2 = [w] * g2
+	a1 := babyjub.NewPoint().Mul(w, g1)
+	a2 := babyjub.NewPoint().Mul(w, g2)
+	c, err := dleqChallenge(g1, h1, g2, h2, a1, a2)
+	if err != nil {
+		return nil, err
+	}
+	// s = w + c * x
+	s := new(big.Int).Mul(c, x)
+	s.Add(s, w)
+	s.Mod(s, babyjub.SubOrder)
+	return &DLEQProof{Challenge: c, Response: s}, nil
+}
+
+// VerifyDLEQ checks the proof of the knowledge of x such that h1 = [x] * g1
+// and h2 = [x] * g2. Every point must be in the curve subgroup and the
+// response must be reduced to the subgroup order, so a proof has a single
+// valid encoding.
+func VerifyDLEQ(proof *DLEQProof, g1, h1, g2, h2 *babyjub.Point) error {
+	if proof == nil || proof.Challenge == nil || proof.Response == nil {
+		return fmt.Errorf("incomplete proof")
+	}
+	if proof.Response.Sign() < 0 || proof.Response.Cmp(babyjub.SubOrder) >= 0 {
+		return fmt.Errorf("response out of range")
+	}
+	for _, p := range []*babyjub.Point{g1, h1, g2, h2} {
+		if !p.InSubGroup() {
+			return fmt.Errorf("point not in curve subgroup")
+		}
+	}
+	// a1 = [s] * g1 - [c] * h1, a2 = [s] * g2 - [c] * h2
+	a1 := addPoints(babyjub.NewPoint().Mul(proof.Response, g1),
+		negPoint(babyjub.NewPoint().Mul(proof.Challenge, h1)))
+	a2 := addPoints(babyjub.NewPoint().Mul(proof.Response, g2),
+		negPoint(babyjub.NewPoint().Mul(proof.Challenge, h2)))
+	c, err := dleqChallenge(g1, h1, g2, h2, a1, a2)
+	if err != nil {
+		return err
+	}
+	if c.Cmp(proof.Challenge) != 0 {
+		return fmt.Errorf("invalid proof")
+	}
+	return nil
+}
+
+// dleqChallenge computes the Fiat-Shamir challenge of the proof, hashing
+// the domain and every point involved and reducing the result to the subgroup
+// order.
+func dleqChallenge(points ...*babyjub.Point) (*big.Int, error) {
+	inputs := []*big.Int{dleqDomain}
+	for _, p := range points {
+		inputs = append(inputs, p.X, p.Y)
+	}
+	hash, err := MultiPoseidon(inputs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute challenge: %v", err)
+	}
+	return hash.Mod(hash, babyjub.SubOrder), nil
+}
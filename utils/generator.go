@@ -0,0 +1,322 @@
+package utils
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"math/rand/v2"
+	"sort"
+)
+
+// maxGeneratorAttempts is the number of attempts to generate a valid random
+// ballot before giving up.
+const maxGeneratorAttempts = 100
+
+// BallotGenerator generates ballots that meet the rules of a ballot mode for
+// a circuit of a number of fields and a voter weight, so they are accepted by
+// the BallotChecker circuit. It uses the random source provided, so the
+// ballots generated can be reproduced using the same seed. A BallotGenerator
+// is not safe for concurrent use.
+type BallotGenerator struct {
+	mode    *BallotMode
+	nFields int
+	minCost *big.Int
+	maxCost *big.Int // nil if the total cost is not bounded
+	weight  *big.Int
+	rng     *rand.Rand
+}
+
+// NewBallotGenerator returns a BallotGenerator for the ballot mode, the
+// number of fields and the voter weight provided. If the source is nil, a
+// random one is used. If no ballot can be valid, it returns the error of
+// CheckBallot for the ballot with the lowest total cost.
+func NewBallotGenerator(mode *BallotMode, nFields int, weight *big.Int, src rand.Source) (*BallotGenerator, error) {
+	if err := mode.Validate(nFields); err != nil {
+		return nil, fmt.Errorf("invalid ballot mode: %v", err)
+	}
+	if weight == nil {
+		weight = big.NewInt(0)
+	}
+	if src == nil {
+		seed := make([]byte, 16)
+		if _, err := crand.Read(seed); err != nil {
+			return nil, fmt.Errorf("failed to generate random seed: %v", err)
+		}
+		src = rand.NewPCG(binary.LittleEndian.Uint64(seed[:8]), binary.LittleEndian.Uint64(seed[8:]))
+	}
+	g := &BallotGenerator{
+		mode:    mode,
+		nFields: nFields,
+		minCost: mode.minTotalCost(),
+		weight:  weight,
+		rng:     rand.New(src),
+	}
+	// the max total cost is only enforced if it is not zero, and it is the
+	// weight if cost_from_weight is set
+	if maxTotalCost := mode.maxTotalCost(); maxTotalCost.Sign() > 0 {
+		g.maxCost = maxTotalCost
+		if mode.CostFromWeight {
+			g.maxCost = new(big.Int).Set(weight)
+		}
+	}
+	_, lowest := mode.costRange()
+	if g.maxCost != nil && (g.maxCost.Cmp(lowest) < 0 || g.maxCost.Cmp(g.minCost) < 0) {
+		return nil, fmt.Errorf("no valid ballot for max total cost %s", g.maxCost)
+	}
+	// the ballot with the lowest total cost is the one that the weight and
+	// the cost bounds are most likely to reject, so if it is not valid, no
+	// ballot is
+	values := g.fit(g.minCost, true)
+	if values == nil {
+		return nil, fmt.Errorf("no valid ballot for min total cost %s", g.minCost)
+	}
+	if _, err := g.ballot(values); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Random returns a random valid ballot, including the padding fields.
+func (g *BallotGenerator) Random() ([]*big.Int, error) {
+	for attempt := 0; attempt < maxGeneratorAttempts; attempt++ {
+		values, ok := g.random()
+		if !ok {
+			continue
+		}
+		// the rules are independent of the order of the fields
+		g.rng.Shuffle(len(values), func(i, j int) { values[i], values[j] = values[j], values[i] })
+		if fields, err := g.ballot(values); err == nil {
+			return fields, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to generate a valid ballot after %d attempts", maxGeneratorAttempts)
+}
+
+// EdgeCases returns the valid ballots at the boundaries of the ballot mode:
+// the ones with the lowest and highest values allowed, and the ones with the
+// total cost closest to the cost bounds. The ballots are deduplicated.
+func (g *BallotGenerator) EdgeCases() [][]*big.Int {
+	candidates := [][]uint64{
+		g.fit(g.minCost, false),
+		g.fit(g.minCost, true),
+	}
+	if g.maxCost != nil {
+		candidates = append(candidates, g.fit(g.maxCost, false), g.fit(g.maxCost, true))
+	}
+	// the extreme values, which will be valid only if the cost bounds allow it
+	lowest, highest := []uint64{}, []uint64{}
+	for i := uint64(0); i < g.mode.MaxCount; i++ {
+		if g.mode.ForceUniqueness {
+			lowest = append(lowest, g.mode.MinValue+i)
+			highest = append(highest, g.mode.MaxValue-i)
+		} else {
+			lowest = append(lowest, g.mode.MinValue)
+			highest = append(highest, g.mode.MaxValue)
+		}
+	}
+	candidates = append(candidates, lowest, highest)
+
+	ballots := [][]*big.Int{}
+	seen := map[string]bool{}
+	for _, values := range candidates {
+		if values == nil {
+			continue
+		}
+		fields, err := g.ballot(values)
+		if err != nil {
+			continue
+		}
+		key := fmt.Sprint(values)
+		if !seen[key] {
+			seen[key] = true
+			ballots = append(ballots, fields)
+		}
+	}
+	return ballots
+}
+
+// random generates the values of the considered fields one by one, choosing
+// every value at random between the ones that still allow the total cost to
+// be between the bounds. It returns false if it gets stuck.
+func (g *BallotGenerator) random() ([]uint64, bool) {
+	values := []uint64{}
+	used := map[uint64]bool{}
+	acc := big.NewInt(0)
+	for i := uint64(0); i < g.mode.MaxCount; i++ {
+		rest := g.mode.MaxCount - i - 1
+		lo, okLo := g.lowestReaching(acc, rest, used)
+		hi, okHi := g.highestFitting(acc, rest, used)
+		if !okLo || !okHi || lo > hi {
+			return nil, false
+		}
+		value := lo + g.rng.Uint64N(hi-lo+1)
+		if used[value] {
+			// look for the closest unused value in the range
+			found := false
+			for v := value; v <= hi && !found; v++ {
+				if !used[v] {
+					value, found = v, true
+				}
+			}
+			for v := value; v >= lo && v <= hi && !found; v-- {
+				if !used[v] {
+					value, found = v, true
+				}
+			}
+			if !found {
+				return nil, false
+			}
+		}
+		values = append(values, value)
+		acc.Add(acc, g.mode.Cost(value))
+		if g.mode.ForceUniqueness {
+			used[value] = true
+		}
+	}
+	return values, true
+}
+
+// fit generates the values of the considered fields trying to get a total
+// cost as close as possible to the target. If above is set, the total cost
+// will be greater or equal than the target, otherwise it will be lower or
+// equal. It returns nil if it is not possible.
+func (g *BallotGenerator) fit(target *big.Int, above bool) []uint64 {
+	values := []uint64{}
+	used := map[uint64]bool{}
+	acc := big.NewInt(0)
+	for i := uint64(0); i < g.mode.MaxCount; i++ {
+		rest := g.mode.MaxCount - i - 1
+		var value uint64
+		var ok bool
+		if above {
+			// the lowest value that still allows to reach the target
+			value, ok = g.search(func(v uint64) bool {
+				total := new(big.Int).Add(acc, g.mode.Cost(v))
+				return total.Add(total, g.restCost(rest, used, v, true)).Cmp(target) >= 0
+			}, true, used)
+		} else {
+			// the highest value that does not exceed the target
+			value, ok = g.search(func(v uint64) bool {
+				total := new(big.Int).Add(acc, g.mode.Cost(v))
+				return total.Add(total, g.restCost(rest, used, v, false)).Cmp(target) <= 0
+			}, false, used)
+		}
+		if !ok {
+			return nil
+		}
+		values = append(values, value)
+		acc.Add(acc, g.mode.Cost(value))
+		if g.mode.ForceUniqueness {
+			used[value] = true
+		}
+	}
+	return values
+}
+
+// lowestReaching returns the lowest value that allows to reach the min total
+// cost with the rest of fields at their highest values.
+func (g *BallotGenerator) lowestReaching(acc *big.Int, rest uint64, used map[uint64]bool) (uint64, bool) {
+	return g.search(func(v uint64) bool {
+		total := new(big.Int).Add(acc, g.mode.Cost(v))
+		return total.Add(total, g.restCost(rest, used, v, true)).Cmp(g.minCost) >= 0
+	}, true, used)
+}
+
+// highestFitting returns the highest value that does not exceed the max total
+// cost with the rest of fields at their lowest values.
+func (g *BallotGenerator) highestFitting(acc *big.Int, rest uint64, used map[uint64]bool) (uint64, bool) {
+	if g.maxCost == nil {
+		return g.search(func(uint64) bool { return true }, false, used)
+	}
+	return g.search(func(v uint64) bool {
+		total := new(big.Int).Add(acc, g.mode.Cost(v))
+		return total.Add(total, g.restCost(rest, used, v, false)).Cmp(g.maxCost) <= 0
+	}, false, used)
+}
+
+// search looks for the lowest (or the highest) unused value in the value
+// range of the ballot mode that satisfies the monotonic condition provided.
+func (g *BallotGenerator) search(cond func(uint64) bool, lowest bool, used map[uint64]bool) (uint64, bool) {
+	min, max := g.mode.MinValue, g.mode.MaxValue
+	n := int(max - min + 1)
+	var value uint64
+	if lowest {
+		// the condition goes from false to true
+		i := sort.Search(n, func(i int) bool { return cond(min + uint64(i)) })
+		if i == n {
+			return 0, false
+		}
+		value = min + uint64(i)
+		for used[value] {
+			if value == max {
+				return 0, false
+			}
+			value++
+		}
+	} else {
+		// the condition goes from true to false
+		i := sort.Search(n, func(i int) bool { return !cond(min + uint64(i)) })
+		if i == 0 {
+			return 0, false
+		}
+		value = min + uint64(i-1)
+		for used[value] {
+			if value == min {
+				return 0, false
+			}
+			value--
+		}
+	}
+	return value, true
+}
+
+// restCost returns the highest (or the lowest) total cost of n fields that
+// do not use the values already used nor the value provided, if the
+// uniqueness is forced.
+func (g *BallotGenerator) restCost(n uint64, used map[uint64]bool, value uint64, highest bool) *big.Int {
+	total := big.NewInt(0)
+	if !g.mode.ForceUniqueness {
+		v := g.mode.MinValue
+		if highest {
+			v = g.mode.MaxValue
+		}
+		return total.Mul(g.mode.Cost(v), new(big.Int).SetUint64(n))
+	}
+	v := g.mode.MinValue
+	if highest {
+		v = g.mode.MaxValue
+	}
+	for count := uint64(0); count < n; {
+		if !used[v] && v != value {
+			total.Add(total, g.mode.Cost(v))
+			count++
+		}
+		if highest {
+			if v == g.mode.MinValue {
+				break
+			}
+			v--
+		} else {
+			if v == g.mode.MaxValue {
+				break
+			}
+			v++
+		}
+	}
+	return total
+}
+
+// ballot pads the values of the considered fields to the number of fields
+// of the circuit and checks the resulting ballot.
+func (g *BallotGenerator) ballot(values []uint64) ([]*big.Int, error) {
+	fields := make([]*big.Int, len(values))
+	for i, v := range values {
+		fields[i] = new(big.Int).SetUint64(v)
+	}
+	fields = BigIntArrayToN(fields, g.nFields)
+	if err := CheckBallot(fields, g.mode, g.weight); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
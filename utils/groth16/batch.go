@@ -0,0 +1,172 @@
+package groth16
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// Proof contains a Groth16 proof and its public signals, in the format
+// of the snarkjs provers: a JSON ProofData and a JSON array of decimals.
+type Proof struct {
+	Proof      string
+	PubSignals string
+}
+
+// BatchVerificationError is returned by VerifyBatch when some proofs of the
+// batch are invalid, with their indexes in the batch, in order, and their
+// errors.
+type BatchVerificationError struct {
+	Invalid []int
+	Errs    []error
+}
+
+func (e *BatchVerificationError) Error() string {
+	return fmt.Sprintf("%d invalid proofs, proof %d: %v", len(e.Invalid), e.Invalid[0], e.Errs[0])
+}
+
+// VerifyBatch checks the proofs provided with the snarkjs verification key
+// provided. See VerificationKey.VerifyBatch.
+func VerifyBatch(vkey []byte, proofs []*Proof) error {
+	vk, err := ParseVerificationKey(vkey)
+	if err != nil {
+		return err
+	}
+	return vk.VerifyBatch(proofs)
+}
+
+// VerifyBatch checks the proofs provided with a single pairing check of a
+// random linear combination of their verification equations, which is much
+// faster than verifying them one by one. If the batch is invalid, it is
+// split in halves recursively to find the invalid proofs, and a
+// *BatchVerificationError with them is returned.
+func (vk *VerificationKey) VerifyBatch(proofs []*Proof) error {
+	errs := make([]error, len(proofs))
+	parsed := make([]*groth16Proof, 0, len(proofs))
+	signals := make([][]*big.Int, 0, len(proofs))
+	indexes := make([]int, 0, len(proofs))
+	for i, p := range proofs {
+		proof, err := parseGroth16Proof(p.Proof)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		s, err := ParseSignals(p.PubSignals, vk.NPublic)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		parsed = append(parsed, proof)
+		signals = append(signals, s)
+		indexes = append(indexes, i)
+	}
+	if err := vk.bisectBatch(parsed, signals, indexes, errs); err != nil {
+		return err
+	}
+	batchErr := &BatchVerificationError{}
+	for i, err := range errs {
+		if err != nil {
+			batchErr.Invalid = append(batchErr.Invalid, i)
+			batchErr.Errs = append(batchErr.Errs, err)
+		}
+	}
+	if len(batchErr.Invalid) > 0 {
+		return batchErr
+	}
+	return nil
+}
+
+// bisectBatch checks the batch of parsed proofs provided, splitting it in
+// halves recursively while it is invalid, and sets the error of every
+// invalid proof in errs at its index.
+func (vk *VerificationKey) bisectBatch(proofs []*groth16Proof, signals [][]*big.Int,
+	indexes []int, errs []error,
+) error {
+	switch len(proofs) {
+	case 0:
+		return nil
+	case 1:
+		errs[indexes[0]] = vk.verify(proofs[0], signals[0])
+		return nil
+	}
+	valid, err := vk.verifyBatch(proofs, signals)
+	if err != nil || valid {
+		return err
+	}
+	half := len(proofs) / 2
+	if err := vk.bisectBatch(proofs[:half], signals[:half], indexes[:half], errs); err != nil {
+		return err
+	}
+	return vk.bisectBatch(proofs[half:], signals[half:], indexes[half:], errs)
+}
+
+// verifyBatch checks the product of the verification equations of the
+// proofs provided raised to random scalars r_i:
+//
+//	prod(e(r_i * A_i, B_i)) * e(-sum(r_i * vk_x_i), gamma) * e(-sum(r_i * C_i), delta) = e(alpha, beta)^sum(r_i)
+//
+// It only holds for an invalid proof with a negligible probability, since
+// the scalars are unknown to the provers.
+func (vk *VerificationKey) verifyBatch(proofs []*groth16Proof, signals [][]*big.Int) (bool, error) {
+	n := len(proofs)
+	g1 := make([]bn254.G1Affine, n+2)
+	g2 := make([]bn254.G2Affine, n+2)
+	cs := make([]bn254.G1Affine, n)
+	rs := make([]fr.Element, n)
+	// the scalars of the IC points in sum(r_i * vk_x_i)
+	icScalars := make([]fr.Element, vk.NPublic)
+	var rSum fr.Element
+	for i, proof := range proofs {
+		if err := randomBatchScalar(&rs[i]); err != nil {
+			return false, err
+		}
+		rSum.Add(&rSum, &rs[i])
+		g1[i].ScalarMultiplication(&proof.a, rs[i].BigInt(new(big.Int)))
+		g2[i] = proof.b
+		cs[i] = proof.c
+		for j, s := range signals[i] {
+			var v fr.Element
+			v.SetBigInt(s)
+			v.Mul(&v, &rs[i])
+			icScalars[j].Add(&icScalars[j], &v)
+		}
+	}
+	vkX, err := msmG1(vk.ic[1:], icScalars)
+	if err != nil {
+		return false, err
+	}
+	var ic0 bn254.G1Affine
+	ic0.ScalarMultiplication(&vk.ic[0], rSum.BigInt(new(big.Int)))
+	vkX.Add(&vkX, &ic0)
+	g1[n].Neg(&vkX)
+	g2[n] = vk.gamma2
+	c, err := msmG1(cs, rs)
+	if err != nil {
+		return false, err
+	}
+	g1[n+1].Neg(&c)
+	g2[n+1] = vk.delta2
+
+	ml, err := bn254.MillerLoop(g1, g2)
+	if err != nil {
+		return false, err
+	}
+	var expected bn254.GT
+	expected.Exp(vk.alphaBeta, rSum.BigInt(new(big.Int)))
+	res := bn254.FinalExponentiation(&ml)
+	return res.Equal(&expected), nil
+}
+
+// randomBatchScalar sets e to a random scalar of 128 bits, enough for the
+// batch verification soundness and cheaper to multiply than a full one.
+func randomBatchScalar(e *fr.Element) error {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	e.SetBytes(b)
+	return nil
+}
@@ -0,0 +1,41 @@
+// Package groth16 implements the Groth16 prover and verifier of the BN254
+// curve for the snarkjs artifacts in pure Go, so it builds without cgo.
+package groth16
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// ProofData is a Groth16 proof in the snarkjs JSON format.
+type ProofData struct {
+	A        []string   `json:"pi_a"`
+	B        [][]string `json:"pi_b"`
+	C        []string   `json:"pi_c"`
+	Protocol string     `json:"protocol,omitempty"`
+	Curve    string     `json:"curve,omitempty"`
+}
+
+// ParseSignals parses the JSON array of public signals provided, checking
+// that it contains n canonical field elements.
+func ParseSignals(pubSignals string, n int) ([]*big.Int, error) {
+	strs := []string{}
+	if err := json.Unmarshal([]byte(pubSignals), &strs); err != nil {
+		return nil, fmt.Errorf("invalid public signals: %v", err)
+	}
+	if len(strs) != n {
+		return nil, fmt.Errorf("invalid number of public signals: expected %d, got %d", n, len(strs))
+	}
+	values := make([]*big.Int, n)
+	for i, str := range strs {
+		value, ok := new(big.Int).SetString(str, 10)
+		if !ok || value.Sign() < 0 || value.Cmp(fr.Modulus()) >= 0 {
+			return nil, fmt.Errorf("invalid public signal %d: %s", i, str)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
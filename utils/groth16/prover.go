@@ -0,0 +1,181 @@
+package groth16
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+)
+
+// Prove generates a Groth16 proof for the witness provided, returning it in
+// the snarkjs format with the public signals of the witness. It follows the
+// snarkjs prover, evaluating the QAP polynomials over the odd coset of the
+// domain, which is the one used to compute the H points of the zkey.
+func (pk *ZKey) Prove(witness []fr.Element) (*ProofData, []*big.Int, error) {
+	if len(witness) != pk.NVars {
+		return nil, nil, fmt.Errorf("invalid witness size: expected %d, got %d", pk.NVars, len(witness))
+	}
+	h, err := pk.quotientEvaluations(witness)
+	if err != nil {
+		return nil, nil, err
+	}
+	var r, s fr.Element
+	if _, err := r.SetRandom(); err != nil {
+		return nil, nil, err
+	}
+	if _, err := s.SetRandom(); err != nil {
+		return nil, nil, err
+	}
+	bigR, bigS := r.BigInt(new(big.Int)), s.BigInt(new(big.Int))
+
+	// pi_a = alpha1 + sum(w_i * A_i) + r * delta1
+	piA, err := msmG1(pk.a, witness)
+	if err != nil {
+		return nil, nil, err
+	}
+	var tmp1 bn254.G1Affine
+	piA.Add(&piA, &pk.alpha1)
+	piA.Add(&piA, tmp1.ScalarMultiplication(&pk.delta1, bigR))
+	// pi_b = beta2 + sum(w_i * B2_i) + s * delta2
+	piB, err := msmG2(pk.b2, witness)
+	if err != nil {
+		return nil, nil, err
+	}
+	var tmp2 bn254.G2Affine
+	piB.Add(&piB, &pk.beta2)
+	piB.Add(&piB, tmp2.ScalarMultiplication(&pk.delta2, bigS))
+	// the same as pi_b in G1 to compute pi_c
+	piB1, err := msmG1(pk.b1, witness)
+	if err != nil {
+		return nil, nil, err
+	}
+	piB1.Add(&piB1, &pk.beta1)
+	piB1.Add(&piB1, tmp1.ScalarMultiplication(&pk.delta1, bigS))
+	// pi_c = sum(w_i * C_i) + sum(h_i * H_i) + s * pi_a + r * pi_b1 - r * s * delta1
+	piC, err := msmG1(pk.c, witness[pk.NPublic+1:])
+	if err != nil {
+		return nil, nil, err
+	}
+	piH, err := msmG1(pk.h, h)
+	if err != nil {
+		return nil, nil, err
+	}
+	piC.Add(&piC, &piH)
+	piC.Add(&piC, tmp1.ScalarMultiplication(&piA, bigS))
+	piC.Add(&piC, tmp1.ScalarMultiplication(&piB1, bigR))
+	var rs fr.Element
+	rs.Mul(&r, &s)
+	piC.Sub(&piC, tmp1.ScalarMultiplication(&pk.delta1, rs.BigInt(new(big.Int))))
+
+	pubSignals := make([]*big.Int, pk.NPublic)
+	for i := range pubSignals {
+		pubSignals[i] = witness[i+1].BigInt(new(big.Int))
+	}
+	return g16ProofData(&piA, &piB, &piC), pubSignals, nil
+}
+
+// quotientEvaluations returns the evaluations of A * B - C over the odd
+// coset of the domain, where A, B and C are the QAP polynomials of the
+// witness. The C evaluations over the domain are A * B, since the witness
+// satisfies the constraints.
+func (pk *ZKey) quotientEvaluations(witness []fr.Element) ([]fr.Element, error) {
+	n := pk.DomainSize
+	a := make([]fr.Element, n)
+	b := make([]fr.Element, n)
+	c := make([]fr.Element, n)
+	for _, coeff := range pk.coeffs {
+		var v fr.Element
+		v.Mul(&coeff.value, &witness[coeff.signal])
+		if coeff.matrix == 0 {
+			a[coeff.constraint].Add(&a[coeff.constraint], &v)
+		} else {
+			b[coeff.constraint].Add(&b[coeff.constraint], &v)
+		}
+	}
+	for i := range c {
+		c[i].Mul(&a[i], &b[i])
+	}
+	// the odd coset is the domain shifted by a root of unity of order 2n
+	logN := bits.TrailingZeros(uint(n))
+	if logN >= 28 {
+		return nil, fmt.Errorf("unsupported domain size %d", n)
+	}
+	shift, err := fft.Generator(uint64(2 * n))
+	if err != nil {
+		return nil, err
+	}
+	pk.domainOnce.Do(func() {
+		pk.domain = fft.NewDomain(uint64(n), shift)
+	})
+	domain := pk.domain
+	for _, p := range [][]fr.Element{a, b, c} {
+		domain.FFTInverse(p, fft.DIF)
+		domain.FFT(p, fft.DIT, fft.OnCoset())
+	}
+	for i := range a {
+		a[i].Mul(&a[i], &b[i])
+		a[i].Sub(&a[i], &c[i])
+	}
+	return a, nil
+}
+
+// msmG1 returns the multi scalar multiplication of the points and the
+// scalars provided, skipping the points at infinity and the zero scalars.
+func msmG1(points []bn254.G1Affine, scalars []fr.Element) (bn254.G1Affine, error) {
+	ps := make([]bn254.G1Affine, 0, len(points))
+	ss := make([]fr.Element, 0, len(points))
+	for i := range points {
+		if !points[i].IsInfinity() && !scalars[i].IsZero() {
+			ps = append(ps, points[i])
+			ss = append(ss, scalars[i])
+		}
+	}
+	res := bn254.G1Affine{}
+	if len(ps) == 0 {
+		return res, nil
+	}
+	if _, err := res.MultiExp(ps, ss, ecc.MultiExpConfig{}); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// msmG2 is the same as msmG1 for G2 points.
+func msmG2(points []bn254.G2Affine, scalars []fr.Element) (bn254.G2Affine, error) {
+	ps := make([]bn254.G2Affine, 0, len(points))
+	ss := make([]fr.Element, 0, len(points))
+	for i := range points {
+		if !points[i].IsInfinity() && !scalars[i].IsZero() {
+			ps = append(ps, points[i])
+			ss = append(ss, scalars[i])
+		}
+	}
+	res := bn254.G2Affine{}
+	if len(ps) == 0 {
+		return res, nil
+	}
+	if _, err := res.MultiExp(ps, ss, ecc.MultiExpConfig{}); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// g16ProofData returns the proof points provided in the snarkjs format,
+// with projective coordinates where z is 1.
+func g16ProofData(a *bn254.G1Affine, b *bn254.G2Affine, c *bn254.G1Affine) *ProofData {
+	return &ProofData{
+		A: []string{a.X.String(), a.Y.String(), "1"},
+		B: [][]string{
+			{b.X.A0.String(), b.X.A1.String()},
+			{b.Y.A0.String(), b.Y.A1.String()},
+			{"1", "0"},
+		},
+		C:        []string{c.X.String(), c.Y.String(), "1"},
+		Protocol: "groth16",
+		Curve:    "bn128",
+	}
+}
@@ -0,0 +1,407 @@
+package groth16
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/fft"
+)
+
+// snarkjs binary files section types
+const (
+	zkeySectionHeader        = 1
+	zkeySectionGroth16Header = 2
+	zkeySectionIC            = 3
+	zkeySectionCoeffs        = 4
+	zkeySectionA             = 5
+	zkeySectionB1            = 6
+	zkeySectionB2            = 7
+	zkeySectionC             = 8
+	zkeySectionH             = 9
+
+	r1csSectionHeader      = 1
+	r1csSectionConstraints = 2
+
+	wtnsSectionHeader = 1
+	wtnsSectionValues = 2
+)
+
+// ZKey is a Groth16 proving key of the BN254 curve in the snarkjs zkey
+// format.
+type ZKey struct {
+	NVars      int
+	NPublic    int
+	DomainSize int
+
+	alpha1 bn254.G1Affine
+	beta1  bn254.G1Affine
+	beta2  bn254.G2Affine
+	gamma2 bn254.G2Affine
+	delta1 bn254.G1Affine
+	delta2 bn254.G2Affine
+
+	ic []bn254.G1Affine
+	a  []bn254.G1Affine
+	b1 []bn254.G1Affine
+	b2 []bn254.G2Affine
+	c  []bn254.G1Affine
+	h  []bn254.G1Affine
+
+	coeffs []zkeyCoeff
+
+	// domain is the FFT domain over its odd coset, computed on the first
+	// proof
+	domain     *fft.Domain
+	domainOnce sync.Once
+}
+
+// zkeyCoeff is a coefficient of the A (matrix 0) or B (matrix 1) matrices of
+// the QAP of a zkey.
+type zkeyCoeff struct {
+	matrix     uint32
+	constraint uint32
+	signal     uint32
+	value      fr.Element
+}
+
+// R1CS contains the constraints of a circuit in the circom r1cs format.
+type R1CS struct {
+	NWires       int
+	NPubOut      int
+	NPubIn       int
+	NPrvIn       int
+	NConstraints int
+
+	constraints []r1csConstraint
+}
+
+// r1csConstraint is a constraint A * B - C = 0 of a R1CS, where every
+// linear combination maps wire indexes to their coefficients.
+type r1csConstraint [3][]r1csTerm
+
+type r1csTerm struct {
+	wire  uint32
+	coeff fr.Element
+}
+
+// ReadZKey parses the snarkjs zkey file contents provided. Only Groth16
+// keys of the BN254 curve are supported.
+func ReadZKey(data []byte) (*ZKey, error) {
+	sections, err := readBinFile(data, "zkey")
+	if err != nil {
+		return nil, err
+	}
+	header := newBinReader(sections[zkeySectionHeader])
+	if protocol := header.uint32(); protocol != 1 {
+		return nil, fmt.Errorf("unsupported zkey protocol %d, only groth16 is supported", protocol)
+	}
+	r := newBinReader(sections[zkeySectionGroth16Header])
+	if err := r.checkPrime(fp.Modulus()); err != nil {
+		return nil, fmt.Errorf("invalid zkey base field: %v", err)
+	}
+	if err := r.checkPrime(fr.Modulus()); err != nil {
+		return nil, fmt.Errorf("invalid zkey scalar field: %v", err)
+	}
+	zkey := &ZKey{
+		NVars:      int(r.uint32()),
+		NPublic:    int(r.uint32()),
+		DomainSize: int(r.uint32()),
+	}
+	zkey.alpha1 = r.g1()
+	zkey.beta1 = r.g1()
+	zkey.beta2 = r.g2()
+	zkey.gamma2 = r.g2()
+	zkey.delta1 = r.g1()
+	zkey.delta2 = r.g2()
+	if r.err != nil {
+		return nil, fmt.Errorf("invalid zkey header: %v", r.err)
+	}
+	if zkey.DomainSize == 0 || zkey.DomainSize&(zkey.DomainSize-1) != 0 {
+		return nil, fmt.Errorf("invalid zkey domain size %d", zkey.DomainSize)
+	}
+	// read the points of every section
+	g1Sections := []struct {
+		section int
+		points  *[]bn254.G1Affine
+		n       int
+	}{
+		{zkeySectionIC, &zkey.ic, zkey.NPublic + 1},
+		{zkeySectionA, &zkey.a, zkey.NVars},
+		{zkeySectionB1, &zkey.b1, zkey.NVars},
+		{zkeySectionC, &zkey.c, zkey.NVars - zkey.NPublic - 1},
+		{zkeySectionH, &zkey.h, zkey.DomainSize},
+	}
+	for _, s := range g1Sections {
+		r := newBinReader(sections[s.section])
+		*s.points = make([]bn254.G1Affine, s.n)
+		for i := range *s.points {
+			(*s.points)[i] = r.g1()
+		}
+		if r.err != nil {
+			return nil, fmt.Errorf("invalid zkey section %d: %v", s.section, r.err)
+		}
+	}
+	r = newBinReader(sections[zkeySectionB2])
+	zkey.b2 = make([]bn254.G2Affine, zkey.NVars)
+	for i := range zkey.b2 {
+		zkey.b2[i] = r.g2()
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("invalid zkey section %d: %v", zkeySectionB2, r.err)
+	}
+	// the coefficients are stored in Montgomery form multiplied by R, so
+	// they are multiplied by R^-1 after reading them as Montgomery
+	rInv := new(big.Int).Lsh(big.NewInt(1), 256)
+	rInv.ModInverse(rInv, fr.Modulus())
+	var frRInv fr.Element
+	frRInv.SetBigInt(rInv)
+	r = newBinReader(sections[zkeySectionCoeffs])
+	zkey.coeffs = make([]zkeyCoeff, r.uint32())
+	for i := range zkey.coeffs {
+		coeff := zkeyCoeff{matrix: r.uint32(), constraint: r.uint32(), signal: r.uint32()}
+		coeff.value = r.montFr()
+		coeff.value.Mul(&coeff.value, &frRInv)
+		if r.err == nil && (coeff.matrix > 1 || int(coeff.constraint) >= zkey.DomainSize ||
+			int(coeff.signal) >= zkey.NVars) {
+			return nil, fmt.Errorf("invalid zkey coefficient %d", i)
+		}
+		zkey.coeffs[i] = coeff
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("invalid zkey section %d: %v", zkeySectionCoeffs, r.err)
+	}
+	return zkey, nil
+}
+
+// ReadR1CS parses the circom r1cs file contents provided. Only circuits of
+// the BN254 scalar field are supported.
+func ReadR1CS(data []byte) (*R1CS, error) {
+	sections, err := readBinFile(data, "r1cs")
+	if err != nil {
+		return nil, err
+	}
+	r := newBinReader(sections[r1csSectionHeader])
+	if err := r.checkPrime(fr.Modulus()); err != nil {
+		return nil, fmt.Errorf("invalid r1cs field: %v", err)
+	}
+	r1cs := &R1CS{
+		NWires:  int(r.uint32()),
+		NPubOut: int(r.uint32()),
+		NPubIn:  int(r.uint32()),
+		NPrvIn:  int(r.uint32()),
+	}
+	r.uint64() // number of labels
+	r1cs.NConstraints = int(r.uint32())
+	if r.err != nil {
+		return nil, fmt.Errorf("invalid r1cs header: %v", r.err)
+	}
+	r = newBinReader(sections[r1csSectionConstraints])
+	r1cs.constraints = make([]r1csConstraint, r1cs.NConstraints)
+	for i := range r1cs.constraints {
+		for lc := range r1cs.constraints[i] {
+			terms := make([]r1csTerm, r.uint32())
+			for j := range terms {
+				terms[j] = r1csTerm{wire: r.uint32(), coeff: r.fr()}
+				if r.err == nil && int(terms[j].wire) >= r1cs.NWires {
+					return nil, fmt.Errorf("invalid r1cs constraint %d: unknown wire %d", i, terms[j].wire)
+				}
+			}
+			r1cs.constraints[i][lc] = terms
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("invalid r1cs constraints: %v", r.err)
+	}
+	return r1cs, nil
+}
+
+// ReadWitness parses the witness provided in the wtns format, as returned
+// by the CalculateWTNSBin method of the witness calculator.
+func ReadWitness(data []byte) ([]fr.Element, error) {
+	sections, err := readBinFile(data, "wtns")
+	if err != nil {
+		return nil, err
+	}
+	r := newBinReader(sections[wtnsSectionHeader])
+	if err := r.checkPrime(fr.Modulus()); err != nil {
+		return nil, fmt.Errorf("invalid witness field: %v", err)
+	}
+	n := r.uint32()
+	if r.err != nil {
+		return nil, fmt.Errorf("invalid witness header: %v", r.err)
+	}
+	r = newBinReader(sections[wtnsSectionValues])
+	witness := make([]fr.Element, n)
+	for i := range witness {
+		witness[i] = r.fr()
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("invalid witness values: %v", r.err)
+	}
+	return witness, nil
+}
+
+// CheckWitness checks that the witness provided satisfies every constraint
+// of the R1CS, returning an error with the first one that is not satisfied.
+func (r *R1CS) CheckWitness(witness []fr.Element) error {
+	if len(witness) != r.NWires {
+		return fmt.Errorf("invalid witness size: expected %d, got %d", r.NWires, len(witness))
+	}
+	if !witness[0].IsOne() {
+		return fmt.Errorf("invalid witness: the first wire must be 1")
+	}
+	for i, constraint := range r.constraints {
+		var values [3]fr.Element
+		for lc, terms := range constraint {
+			for _, term := range terms {
+				var v fr.Element
+				v.Mul(&term.coeff, &witness[term.wire])
+				values[lc].Add(&values[lc], &v)
+			}
+		}
+		values[0].Mul(&values[0], &values[1])
+		if !values[0].Equal(&values[2]) {
+			return fmt.Errorf("constraint %d not satisfied", i)
+		}
+	}
+	return nil
+}
+
+// readBinFile parses the sections of a binary file of iden3, like the
+// snarkjs zkey or the circom r1cs and wtns files, with the magic provided.
+func readBinFile(data []byte, magic string) (map[int][]byte, error) {
+	if len(data) < 12 || string(data[:4]) != magic {
+		return nil, fmt.Errorf("invalid %s file", magic)
+	}
+	nSections := binary.LittleEndian.Uint32(data[8:12])
+	sections := map[int][]byte{}
+	offset := uint64(12)
+	for i := uint32(0); i < nSections; i++ {
+		if uint64(len(data)) < offset+12 {
+			return nil, fmt.Errorf("invalid %s file: truncated section header", magic)
+		}
+		section := int(binary.LittleEndian.Uint32(data[offset:]))
+		size := binary.LittleEndian.Uint64(data[offset+4:])
+		offset += 12
+		if uint64(len(data))-offset < size {
+			return nil, fmt.Errorf("invalid %s file: truncated section %d", magic, section)
+		}
+		sections[section] = data[offset : offset+size]
+		offset += size
+	}
+	return sections, nil
+}
+
+// binReader reads little-endian values from a section of a binary file,
+// keeping the first error so it can be checked once after many reads.
+type binReader struct {
+	data []byte
+	err  error
+}
+
+func newBinReader(data []byte) *binReader {
+	r := &binReader{data: data}
+	if data == nil {
+		r.err = fmt.Errorf("missing section")
+	}
+	return r
+}
+
+func (r *binReader) next(n int) []byte {
+	if r.err != nil {
+		return make([]byte, n)
+	}
+	if len(r.data) < n {
+		r.err = fmt.Errorf("unexpected end of section")
+		return make([]byte, n)
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *binReader) uint32() uint32 {
+	return binary.LittleEndian.Uint32(r.next(4))
+}
+
+func (r *binReader) uint64() uint64 {
+	return binary.LittleEndian.Uint64(r.next(8))
+}
+
+// checkPrime reads the size and the value of a prime and checks that it is
+// the one provided.
+func (r *binReader) checkPrime(expected *big.Int) error {
+	n8 := r.uint32()
+	if r.err == nil && n8 != 32 {
+		return fmt.Errorf("unsupported field size %d", n8)
+	}
+	b := r.next(32)
+	if r.err != nil {
+		return r.err
+	}
+	if prime := new(big.Int).SetBytes(reverseBytes(b)); prime.Cmp(expected) != 0 {
+		return fmt.Errorf("unsupported prime %s", prime)
+	}
+	return nil
+}
+
+// fr reads a scalar field element in canonical form.
+func (r *binReader) fr() fr.Element {
+	var b [fr.Bytes]byte
+	copy(b[:], r.next(fr.Bytes))
+	e, err := fr.LittleEndian.Element(&b)
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+	return e
+}
+
+// montFr reads a scalar field element in Montgomery form, which is the
+// internal representation of gnark-crypto.
+func (r *binReader) montFr() fr.Element {
+	b := r.next(fr.Bytes)
+	return fr.Element{
+		binary.LittleEndian.Uint64(b[0:8]),
+		binary.LittleEndian.Uint64(b[8:16]),
+		binary.LittleEndian.Uint64(b[16:24]),
+		binary.LittleEndian.Uint64(b[24:32]),
+	}
+}
+
+// montFp reads a base field element in Montgomery form.
+func (r *binReader) montFp() fp.Element {
+	b := r.next(fp.Bytes)
+	return fp.Element{
+		binary.LittleEndian.Uint64(b[0:8]),
+		binary.LittleEndian.Uint64(b[8:16]),
+		binary.LittleEndian.Uint64(b[16:24]),
+		binary.LittleEndian.Uint64(b[24:32]),
+	}
+}
+
+// g1 reads a G1 point with its coordinates in Montgomery form. The point at
+// infinity is encoded as (0, 0), like in gnark-crypto.
+func (r *binReader) g1() bn254.G1Affine {
+	return bn254.G1Affine{X: r.montFp(), Y: r.montFp()}
+}
+
+// g2 reads a G2 point with its coordinates in Montgomery form.
+func (r *binReader) g2() bn254.G2Affine {
+	p := bn254.G2Affine{}
+	p.X.A0, p.X.A1 = r.montFp(), r.montFp()
+	p.Y.A0, p.Y.A1 = r.montFp(), r.montFp()
+	return p
+}
+
+func reverseBytes(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
@@ -0,0 +1,186 @@
+package groth16
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/iden3/go-iden3-crypto/keccak256"
+)
+
+// DefaultSolidityVerifierName is the name of the contracts generated by
+// SolidityVerifier if no other name is provided, the same as the snarkjs
+// one.
+const DefaultSolidityVerifierName = "Groth16Verifier"
+
+//go:embed verifier_groth16.sol.tmpl
+var solidityVerifierTemplate string
+
+var (
+	solidityVerifier   = template.Must(template.New("verifier").Parse(solidityVerifierTemplate))
+	solidityIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+)
+
+// solidityG1 and solidityG2 are the coordinates of the points of the
+// verification key in the contract, with the G2 ones in the EIP-197 order:
+// x1 and y1 are the imaginary parts and x2 and y2 the real ones.
+type solidityG1 struct {
+	X, Y string
+}
+
+type solidityG2 struct {
+	X1, X2, Y1, Y2 string
+}
+
+// soliditySignal is a public signal of the contract, with its IC point index
+// and its offset in the calldata array.
+type soliditySignal struct {
+	Index, Offset int
+}
+
+// SolidityVerifier returns the source of a Solidity contract with the name
+// provided that verifies the Groth16 proofs of the snarkjs verification key
+// JSON provided, like the *_vkey.json files of the artifacts. The contract
+// has the same verifyProof function as the ones exported by snarkjs, see
+// SolidityCalldata for its arguments.
+func SolidityVerifier(vkey []byte, name string) (string, error) {
+	if name == "" {
+		name = DefaultSolidityVerifierName
+	}
+	if !solidityIdentifier.MatchString(name) {
+		return "", fmt.Errorf("invalid contract name %q", name)
+	}
+	vk, err := ParseVerificationKey(vkey)
+	if err != nil {
+		return "", err
+	}
+	// the verifyProof function would have a zero length array argument
+	if vk.NPublic == 0 {
+		return "", fmt.Errorf("verification key without public signals")
+	}
+	data := struct {
+		Name               string
+		NPublic            int
+		Alpha              solidityG1
+		Beta, Gamma, Delta solidityG2
+		IC                 []solidityG1
+		Signals            []soliditySignal
+	}{
+		Name:    name,
+		NPublic: vk.NPublic,
+		Alpha:   newSolidityG1(&vk.alpha1),
+		Beta:    newSolidityG2(&vk.beta2),
+		Gamma:   newSolidityG2(&vk.gamma2),
+		Delta:   newSolidityG2(&vk.delta2),
+	}
+	for i := range vk.ic {
+		data.IC = append(data.IC, newSolidityG1(&vk.ic[i]))
+	}
+	for i := 0; i < vk.NPublic; i++ {
+		data.Signals = append(data.Signals, soliditySignal{Index: i + 1, Offset: i * 32})
+	}
+	source := &strings.Builder{}
+	if err := solidityVerifier.Execute(source, data); err != nil {
+		return "", err
+	}
+	return source.String(), nil
+}
+
+func newSolidityG1(p *bn254.G1Affine) solidityG1 {
+	return solidityG1{X: p.X.String(), Y: p.Y.String()}
+}
+
+func newSolidityG2(p *bn254.G2Affine) solidityG2 {
+	return solidityG2{X1: p.X.A1.String(), X2: p.X.A0.String(), Y1: p.Y.A1.String(), Y2: p.Y.A0.String()}
+}
+
+// SolidityCalldata contains the arguments of the verifyProof function of the
+// contracts generated by SolidityVerifier for a proof:
+//
+//	verifyProof(uint[2] a, uint[2][2] b, uint[2] c, uint[N] input)
+type SolidityCalldata struct {
+	A [2]*big.Int
+	// B has the coordinates of the proof point swapped, since the EVM
+	// pairing precompile expects the imaginary part first (EIP-197).
+	B     [2][2]*big.Int
+	C     [2]*big.Int
+	Input []*big.Int
+}
+
+// NewSolidityCalldata returns the arguments of the verifyProof function of
+// the contracts generated by SolidityVerifier for the proof and the public
+// signals provided, in the snarkjs format.
+func NewSolidityCalldata(proofData, pubSignals string) (*SolidityCalldata, error) {
+	proof, err := parseGroth16Proof(proofData)
+	if err != nil {
+		return nil, err
+	}
+	strs := []string{}
+	if err := json.Unmarshal([]byte(pubSignals), &strs); err != nil {
+		return nil, fmt.Errorf("invalid public signals: %v", err)
+	}
+	input, err := ParseSignals(pubSignals, len(strs))
+	if err != nil {
+		return nil, err
+	}
+	if len(input) == 0 {
+		return nil, fmt.Errorf("proof without public signals")
+	}
+	fpBigInt := func(e interface{ BigInt(*big.Int) *big.Int }) *big.Int {
+		return e.BigInt(new(big.Int))
+	}
+	return &SolidityCalldata{
+		A: [2]*big.Int{fpBigInt(&proof.a.X), fpBigInt(&proof.a.Y)},
+		B: [2][2]*big.Int{
+			{fpBigInt(&proof.b.X.A1), fpBigInt(&proof.b.X.A0)},
+			{fpBigInt(&proof.b.Y.A1), fpBigInt(&proof.b.Y.A0)},
+		},
+		C:     [2]*big.Int{fpBigInt(&proof.c.X), fpBigInt(&proof.c.Y)},
+		Input: input,
+	}, nil
+}
+
+// Selector returns the selector of the verifyProof function for the number
+// of public signals of the calldata.
+func (c *SolidityCalldata) Selector() []byte {
+	signature := fmt.Sprintf("verifyProof(uint256[2],uint256[2][2],uint256[2],uint256[%d])", len(c.Input))
+	return keccak256.Hash([]byte(signature))[:4]
+}
+
+// Pack returns the ABI encoded call to the verifyProof function: its
+// selector followed by the arguments, which are static arrays, so they are
+// encoded in place as 32 bytes words.
+func (c *SolidityCalldata) Pack() []byte {
+	words := c.words()
+	b := make([]byte, 4, 4+32*len(words))
+	copy(b, c.Selector())
+	for _, w := range words {
+		b = append(b, w.FillBytes(make([]byte, 32))...)
+	}
+	return b
+}
+
+// String returns the arguments in the format of the snarkjs
+// `zkey export soliditycalldata` command.
+func (c *SolidityCalldata) String() string {
+	hex := func(values ...*big.Int) string {
+		strs := make([]string, len(values))
+		for i, v := range values {
+			strs[i] = fmt.Sprintf(`"0x%064x"`, v)
+		}
+		return "[" + strings.Join(strs, ",") + "]"
+	}
+	return hex(c.A[:]...) + ",[" + hex(c.B[0][:]...) + "," + hex(c.B[1][:]...) + "]," +
+		hex(c.C[:]...) + "," + hex(c.Input...)
+}
+
+// words returns the arguments in the order of the verifyProof function.
+func (c *SolidityCalldata) words() []*big.Int {
+	words := []*big.Int{c.A[0], c.A[1], c.B[0][0], c.B[0][1], c.B[1][0], c.B[1][1], c.C[0], c.C[1]}
+	return append(words, c.Input...)
+}
@@ -0,0 +1,230 @@
+package groth16
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// VerificationKey is a Groth16 verification key of the BN254 curve in the
+// snarkjs format. It is parsed once, with the pairing of alpha and beta
+// precomputed, so it can verify many proofs of the same circuit without
+// parsing it again. It is safe to use it from many goroutines concurrently.
+type VerificationKey struct {
+	// NPublic is the number of public signals of the proofs.
+	NPublic int
+
+	alpha1                bn254.G1Affine
+	beta2, gamma2, delta2 bn254.G2Affine
+	ic                    []bn254.G1Affine
+	alphaBeta             bn254.GT
+}
+
+// vkeyJSON is the snarkjs verification key JSON format.
+type vkeyJSON struct {
+	Protocol string     `json:"protocol"`
+	Curve    string     `json:"curve"`
+	NPublic  int        `json:"nPublic"`
+	Alpha1   []string   `json:"vk_alpha_1"`
+	Beta2    [][]string `json:"vk_beta_2"`
+	Gamma2   [][]string `json:"vk_gamma_2"`
+	Delta2   [][]string `json:"vk_delta_2"`
+	IC       [][]string `json:"IC"`
+}
+
+// groth16Proof contains the points of a parsed Groth16 proof.
+type groth16Proof struct {
+	a, c bn254.G1Affine
+	b    bn254.G2Affine
+}
+
+// ParseVerificationKey parses the snarkjs verification key JSON provided,
+// like the *_vkey.json files of the artifacts, checking that its points are
+// valid.
+func ParseVerificationKey(data []byte) (*VerificationKey, error) {
+	raw := vkeyJSON{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid verification key: %v", err)
+	}
+	if raw.Protocol != "" && raw.Protocol != "groth16" {
+		return nil, fmt.Errorf("unsupported protocol %s", raw.Protocol)
+	}
+	if raw.Curve != "" && raw.Curve != "bn128" {
+		return nil, fmt.Errorf("unsupported curve %s", raw.Curve)
+	}
+	if raw.NPublic < 0 || len(raw.IC) != raw.NPublic+1 {
+		return nil, fmt.Errorf("invalid verification key: %d IC points for %d public signals",
+			len(raw.IC), raw.NPublic)
+	}
+	vk := &VerificationKey{NPublic: raw.NPublic, ic: make([]bn254.G1Affine, len(raw.IC))}
+	var err error
+	if vk.alpha1, err = parseG1(raw.Alpha1); err != nil {
+		return nil, fmt.Errorf("invalid vk_alpha_1: %v", err)
+	}
+	if vk.beta2, err = parseG2(raw.Beta2); err != nil {
+		return nil, fmt.Errorf("invalid vk_beta_2: %v", err)
+	}
+	if vk.gamma2, err = parseG2(raw.Gamma2); err != nil {
+		return nil, fmt.Errorf("invalid vk_gamma_2: %v", err)
+	}
+	if vk.delta2, err = parseG2(raw.Delta2); err != nil {
+		return nil, fmt.Errorf("invalid vk_delta_2: %v", err)
+	}
+	for i := range raw.IC {
+		if vk.ic[i], err = parseG1(raw.IC[i]); err != nil {
+			return nil, fmt.Errorf("invalid IC %d: %v", i, err)
+		}
+	}
+	if vk.alphaBeta, err = bn254.Pair([]bn254.G1Affine{vk.alpha1}, []bn254.G2Affine{vk.beta2}); err != nil {
+		return nil, err
+	}
+	return vk, nil
+}
+
+// Verify checks the proof and the public signals provided, in the snarkjs
+// format. The public signals must be canonical field elements and there
+// must be NPublic of them.
+func (vk *VerificationKey) Verify(proofData, pubSignals string) error {
+	proof, err := parseGroth16Proof(proofData)
+	if err != nil {
+		return err
+	}
+	signals, err := ParseSignals(pubSignals, vk.NPublic)
+	if err != nil {
+		return err
+	}
+	return vk.verify(proof, signals)
+}
+
+// verify checks e(A, B) = e(alpha, beta) * e(vk_x, gamma) * e(C, delta),
+// computing the three pairings left with a single final exponentiation.
+func (vk *VerificationKey) verify(proof *groth16Proof, signals []*big.Int) error {
+	vkX, err := vk.linearCombination(signals)
+	if err != nil {
+		return err
+	}
+	vkX.Neg(&vkX)
+	var negC bn254.G1Affine
+	negC.Neg(&proof.c)
+	ml, err := bn254.MillerLoop(
+		[]bn254.G1Affine{proof.a, vkX, negC},
+		[]bn254.G2Affine{proof.b, vk.gamma2, vk.delta2},
+	)
+	if err != nil {
+		return err
+	}
+	if res := bn254.FinalExponentiation(&ml); !res.Equal(&vk.alphaBeta) {
+		return fmt.Errorf("invalid proof")
+	}
+	return nil
+}
+
+// linearCombination returns vk_x = IC_0 + sum(s_i * IC_i+1) for the public
+// signals provided.
+func (vk *VerificationKey) linearCombination(signals []*big.Int) (bn254.G1Affine, error) {
+	scalars := make([]fr.Element, len(signals))
+	for i := range signals {
+		scalars[i].SetBigInt(signals[i])
+	}
+	vkX, err := msmG1(vk.ic[1:], scalars)
+	if err != nil {
+		return vkX, err
+	}
+	vkX.Add(&vkX, &vk.ic[0])
+	return vkX, nil
+}
+
+// parseGroth16Proof parses the proof provided in the snarkjs format,
+// checking that its points are valid.
+func parseGroth16Proof(proofData string) (*groth16Proof, error) {
+	data := ProofData{}
+	if err := json.Unmarshal([]byte(proofData), &data); err != nil {
+		return nil, fmt.Errorf("invalid proof data: %v", err)
+	}
+	var err error
+	proof := &groth16Proof{}
+	if proof.a, err = parseG1(data.A); err != nil {
+		return nil, fmt.Errorf("invalid pi_a: %v", err)
+	}
+	if proof.b, err = parseG2(data.B); err != nil {
+		return nil, fmt.Errorf("invalid pi_b: %v", err)
+	}
+	if proof.c, err = parseG1(data.C); err != nil {
+		return nil, fmt.Errorf("invalid pi_c: %v", err)
+	}
+	return proof, nil
+}
+
+// parseG1 parses a G1 point in the snarkjs format, [x, y, z] with z = 1, or
+// z = 0 for the point at infinity.
+func parseG1(coords []string) (bn254.G1Affine, error) {
+	p := bn254.G1Affine{}
+	if len(coords) != 3 {
+		return p, fmt.Errorf("expected 3 coordinates, got %d", len(coords))
+	}
+	if coords[2] == "0" {
+		return p, nil
+	}
+	if coords[2] != "1" {
+		return p, fmt.Errorf("unsupported z coordinate %s", coords[2])
+	}
+	var err error
+	if p.X, err = parseFp(coords[0]); err != nil {
+		return p, err
+	}
+	if p.Y, err = parseFp(coords[1]); err != nil {
+		return p, err
+	}
+	if !p.IsOnCurve() {
+		return p, fmt.Errorf("point not in curve")
+	}
+	return p, nil
+}
+
+// parseG2 parses a G2 point in the snarkjs format, [[x0, x1], [y0, y1],
+// [z0, z1]] with z = 1, or z = 0 for the point at infinity.
+func parseG2(coords [][]string) (bn254.G2Affine, error) {
+	p := bn254.G2Affine{}
+	if len(coords) != 3 {
+		return p, fmt.Errorf("expected 3 coordinates, got %d", len(coords))
+	}
+	for _, c := range coords {
+		if len(c) != 2 {
+			return p, fmt.Errorf("invalid coordinate of %d elements", len(c))
+		}
+	}
+	if coords[2][0] == "0" && coords[2][1] == "0" {
+		return p, nil
+	}
+	if coords[2][0] != "1" || coords[2][1] != "0" {
+		return p, fmt.Errorf("unsupported z coordinate [%s, %s]", coords[2][0], coords[2][1])
+	}
+	var err error
+	for i, e := range []*fp.Element{&p.X.A0, &p.X.A1, &p.Y.A0, &p.Y.A1} {
+		if *e, err = parseFp(coords[i/2][i%2]); err != nil {
+			return p, err
+		}
+	}
+	if !p.IsOnCurve() {
+		return p, fmt.Errorf("point not in curve")
+	}
+	if !p.IsInSubGroup() {
+		return p, fmt.Errorf("point not in subgroup")
+	}
+	return p, nil
+}
+
+// parseFp parses a canonical base field element in decimal.
+func parseFp(s string) (fp.Element, error) {
+	e := fp.Element{}
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok || v.Sign() < 0 || v.Cmp(fp.Modulus()) >= 0 {
+		return e, fmt.Errorf("invalid coordinate %s", s)
+	}
+	e.SetBigInt(v)
+	return e, nil
+}
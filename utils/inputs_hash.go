@@ -0,0 +1,230 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/iden3/go-iden3-crypto/mimc7"
+)
+
+// InputsHasher computes the inputs hash of the hashed inputs variants of the
+// ballot proof circuit from its preimage.
+type InputsHasher interface {
+	Hash(preimage []*big.Int) (*big.Int, error)
+}
+
+// MiMC7Hasher is the InputsHasher of the ballot_proof_mimc.circom circuit,
+// which uses the MultiMiMC7 template with 91 rounds and a zero key.
+type MiMC7Hasher struct{}
+
+// Hash implements the InputsHasher interface.
+func (MiMC7Hasher) Hash(preimage []*big.Int) (*big.Int, error) {
+	return mimc7.Hash(preimage, nil)
+}
+
+// PoseidonHasher is the InputsHasher of the ballot_proof_poseidon.circom
+// circuit, which uses the MultiPoseidonTree template.
+type PoseidonHasher struct{}
+
+// Hash implements the InputsHasher interface.
+func (PoseidonHasher) Hash(preimage []*big.Int) (*big.Int, error) {
+	return MultiPoseidonTree(preimage...)
+}
+
+// EncryptedBallot contains the public data of a ballot cast by a voter: the
+// vote ID and the cipherfields in the circuit format.
+type EncryptedBallot struct {
+	VoteID       *big.Int
+	Cipherfields [][][]string
+}
+
+// PreimageElement is an element of the inputs hash preimage, identified by
+// its position and the name of the circuit signal it comes from.
+type PreimageElement struct {
+	Index int
+	Name  string
+	Value *big.Int
+}
+
+// HasherForVariant returns the InputsHasher of the ballot proof circuit
+// variant provided, or nil if the variant does not hash its inputs.
+func HasherForVariant(variant BallotProofVariant) (InputsHasher, error) {
+	switch variant {
+	case BallotProofPlain:
+		return nil, nil
+	case BallotProofMiMC:
+		return MiMC7Hasher{}, nil
+	case BallotProofPoseidon:
+		return PoseidonHasher{}, nil
+	default:
+		return nil, fmt.Errorf("unknown ballot proof variant: %s", variant)
+	}
+}
+
+// InputsHashDecomposition returns the elements of the inputs hash preimage
+// in the order used by the hashed inputs circuits, named after the circuit
+// signals they come from:
+//  1. process_id
+//  2. the ballot mode: max_count, force_uniqueness, max_value, min_value,
+//     max_total_cost, min_total_cost, cost_exp and cost_from_weight
+//  3. the encryption key: pk[0] and pk[1]
+//  4. address
+//  5. vote_id
+//  6. the coordinates of every cipherfield: cipherfields[f][i][j]
+//  7. weight
+func InputsHashDecomposition(process *Process, voter *Voter, ballot *EncryptedBallot) ([]*PreimageElement, error) {
+	if process.ID == nil || process.BallotMode == nil || process.EncryptionKey == nil {
+		return nil, fmt.Errorf("incomplete process")
+	}
+	if voter.Address == nil {
+		return nil, fmt.Errorf("missing voter address")
+	}
+	if ballot.VoteID == nil {
+		return nil, fmt.Errorf("missing vote ID")
+	}
+	weight := voter.Weight
+	if weight == nil {
+		weight = big.NewInt(0)
+	}
+	elements := []*PreimageElement{}
+	add := func(name string, value *big.Int) {
+		elements = append(elements, &PreimageElement{Index: len(elements), Name: name, Value: value})
+	}
+	add("process_id", process.ID)
+	keys := ballotModeKeys()
+	for i, value := range process.BallotMode.BigInts() {
+		add(keys[i], value)
+	}
+	add("pk[0]", process.EncryptionKey.X)
+	add("pk[1]", process.EncryptionKey.Y)
+	add("address", voter.Address)
+	add("vote_id", ballot.VoteID)
+	for f, cipherfield := range ballot.Cipherfields {
+		if len(cipherfield) != 2 {
+			return nil, fmt.Errorf("invalid cipherfield %d format", f)
+		}
+		for i, point := range cipherfield {
+			if len(point) != 2 {
+				return nil, fmt.Errorf("invalid cipherfield %d format", f)
+			}
+			for j, coord := range point {
+				value, ok := new(big.Int).SetString(coord, 10)
+				if !ok {
+					return nil, fmt.Errorf("invalid cipherfield %d coordinate: %s", f, coord)
+				}
+				add(fmt.Sprintf("cipherfields[%d][%d][%d]", f, i, j), value)
+			}
+		}
+	}
+	add("weight", weight)
+	return elements, nil
+}
+
+// InputsHashPreimage returns the inputs hash preimage in the order used by
+// the hashed inputs circuits. See InputsHashDecomposition.
+func InputsHashPreimage(process *Process, voter *Voter, ballot *EncryptedBallot) ([]*big.Int, error) {
+	elements, err := InputsHashDecomposition(process, voter, ballot)
+	if err != nil {
+		return nil, err
+	}
+	preimage := make([]*big.Int, len(elements))
+	for i, element := range elements {
+		preimage[i] = element.Value
+	}
+	return preimage, nil
+}
+
+// InputsHash returns the inputs hash of the ballot of the voter in the
+// process provided, computed with the hasher provided.
+func InputsHash(hasher InputsHasher, process *Process, voter *Voter, ballot *EncryptedBallot) (*big.Int, error) {
+	preimage, err := InputsHashPreimage(process, voter, ballot)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := hasher.Hash(preimage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute inputs hash: %v", err)
+	}
+	return hash, nil
+}
+
+// NewProcessInputsHasher returns a MultiPoseidonHasher with the elements of
+// the inputs hash preimage that are constant for every ballot of the process
+// provided already written: the process ID, the ballot mode and the
+// encryption key. The inputs hash of every ballot of the process can then be
+// computed from it with PoseidonInputsHash.
+func NewProcessInputsHasher(process *Process) (*MultiPoseidonHasher, error) {
+	if process.ID == nil || process.BallotMode == nil || process.EncryptionKey == nil {
+		return nil, fmt.Errorf("incomplete process")
+	}
+	h := NewMultiPoseidonHasher()
+	if err := h.Write(process.ID); err != nil {
+		return nil, err
+	}
+	if err := h.Write(process.BallotMode.BigInts()...); err != nil {
+		return nil, err
+	}
+	if err := h.Write(process.EncryptionKey.X, process.EncryptionKey.Y); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// PoseidonInputsHash returns the inputs hash of the ballot_proof_poseidon
+// circuit for the ballot of the voter provided, finishing the hash of the
+// process hasher returned by NewProcessInputsHasher, which is not modified.
+func PoseidonInputsHash(processHasher *MultiPoseidonHasher, voter *Voter, ballot *EncryptedBallot) (*big.Int, error) {
+	// process ID, ballot mode and encryption key
+	if n := 3 + len(ballotModeKeys()); processHasher.Len() != n {
+		return nil, fmt.Errorf("invalid process hasher: expected %d inputs, got %d", n, processHasher.Len())
+	}
+	if voter.Address == nil {
+		return nil, fmt.Errorf("missing voter address")
+	}
+	if ballot.VoteID == nil {
+		return nil, fmt.Errorf("missing vote ID")
+	}
+	weight := voter.Weight
+	if weight == nil {
+		weight = big.NewInt(0)
+	}
+	h := processHasher.Snapshot()
+	if err := h.Write(voter.Address, ballot.VoteID); err != nil {
+		return nil, err
+	}
+	for f, cipherfield := range ballot.Cipherfields {
+		coords, err := cipherfieldBigInts(cipherfield)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cipherfield %d: %v", f, err)
+		}
+		if err := h.Write(coords...); err != nil {
+			return nil, err
+		}
+	}
+	if err := h.Write(weight); err != nil {
+		return nil, err
+	}
+	return h.Sum()
+}
+
+// cipherfieldBigInts returns the coordinates of the cipherfield provided in
+// the circuit format.
+func cipherfieldBigInts(cipherfield [][]string) ([]*big.Int, error) {
+	if len(cipherfield) != 2 {
+		return nil, fmt.Errorf("invalid format")
+	}
+	coords := []*big.Int{}
+	for _, point := range cipherfield {
+		if len(point) != 2 {
+			return nil, fmt.Errorf("invalid format")
+		}
+		for _, coord := range point {
+			value, ok := new(big.Int).SetString(coord, 10)
+			if !ok {
+				return nil, fmt.Errorf("invalid coordinate: %s", coord)
+			}
+			coords = append(coords, value)
+		}
+	}
+	return coords, nil
+}
@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/iden3/go-iden3-crypto/constants"
+	"github.com/iden3/go-iden3-crypto/poseidon"
+)
+
+// multiPoseidonChunkSize is the number of elements hashed together by every
+// node of the MultiPoseidon tree.
+const multiPoseidonChunkSize = 16
+
+// MultiPoseidonHasher computes the MultiPoseidonTree hash of its inputs
+// incrementally, so they do not need to be known up front. Every time a chunk
+// of a level of the tree is complete, it is hashed and its hash is written to
+// the next level, so only the incomplete chunk of every level is kept.
+//
+// The state of the hasher can be copied with Snapshot, for example to
+// compute the hash of a common prefix once and finish the hash of every
+// input sequence that starts with it from a copy.
+type MultiPoseidonHasher struct {
+	// levels contains the incomplete chunk of every level of the tree
+	levels [][]*big.Int
+	// counts contains the number of elements written to every level
+	counts []int
+}
+
+// NewMultiPoseidonHasher returns an empty MultiPoseidonHasher.
+func NewMultiPoseidonHasher() *MultiPoseidonHasher {
+	h := &MultiPoseidonHasher{}
+	h.Reset()
+	return h
+}
+
+// Write adds the inputs provided to the hasher. It fails if any input is
+// not a field element.
+func (h *MultiPoseidonHasher) Write(inputs ...*big.Int) error {
+	for _, input := range inputs {
+		if input == nil || input.Sign() < 0 || input.Cmp(constants.Q) >= 0 {
+			return fmt.Errorf("input %v is not inside the finite field", input)
+		}
+		if err := h.write(0, new(big.Int).Set(input)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sum returns the MultiPoseidonTree hash of the inputs written, which is the
+// same as the MultiPoseidon one for up to 256 inputs. It does not change the
+// state of the hasher, so more inputs can be written after it.
+func (h *MultiPoseidonHasher) Sum() (*big.Int, error) {
+	if h.Len() == 0 {
+		return nil, fmt.Errorf("no inputs provided")
+	}
+	s := h.Snapshot()
+	for level := 0; ; level++ {
+		// the root of the tree is the only hash of the last level
+		if level > 0 && s.counts[level] == 1 {
+			return s.levels[level][0], nil
+		}
+		// hash the incomplete chunk of the level as the last one
+		if len(s.levels[level]) > 0 {
+			hash, err := poseidon.Hash(s.levels[level])
+			if err != nil {
+				return nil, err
+			}
+			s.levels[level] = nil
+			s.push(level+1, hash)
+		}
+	}
+}
+
+// Reset removes every input written to the hasher.
+func (h *MultiPoseidonHasher) Reset() {
+	h.levels = [][]*big.Int{nil}
+	h.counts = []int{0}
+}
+
+// Len returns the number of inputs written to the hasher.
+func (h *MultiPoseidonHasher) Len() int {
+	return h.counts[0]
+}
+
+// Snapshot returns an independent copy of the hasher with the same state.
+func (h *MultiPoseidonHasher) Snapshot() *MultiPoseidonHasher {
+	s := &MultiPoseidonHasher{
+		levels: make([][]*big.Int, len(h.levels)),
+		counts: append([]int{}, h.counts...),
+	}
+	for i, level := range h.levels {
+		s.levels[i] = append([]*big.Int{}, level...)
+	}
+	return s
+}
+
+// Restore sets the state of the hasher to the one of the snapshot provided.
+func (h *MultiPoseidonHasher) Restore(snapshot *MultiPoseidonHasher) {
+	s := snapshot.Snapshot()
+	h.levels, h.counts = s.levels, s.counts
+}
+
+// write adds the value provided to the level provided, hashing its chunk if
+// it is complete.
+func (h *MultiPoseidonHasher) write(level int, value *big.Int) error {
+	h.push(level, value)
+	if len(h.levels[level]) < multiPoseidonChunkSize {
+		return nil
+	}
+	hash, err := poseidon.Hash(h.levels[level])
+	if err != nil {
+		return err
+	}
+	h.levels[level] = nil
+	return h.write(level+1, hash)
+}
+
+// push appends the value provided to the incomplete chunk of the level
+// provided, creating the level if it does not exist.
+func (h *MultiPoseidonHasher) push(level int, value *big.Int) {
+	if level == len(h.levels) {
+		h.levels = append(h.levels, nil)
+		h.counts = append(h.counts, 0)
+	}
+	h.levels[level] = append(h.levels[level], value)
+	h.counts[level]++
+}
@@ -0,0 +1,273 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	"github.com/iden3/go-iden3-crypto/constants"
+)
+
+const (
+	// RulePadding: the fields not considered by the max count should be zero.
+	// The circuits do not constrain these fields, so it is not enforced.
+	RulePadding BallotRule = "padding"
+	// RuleVoteID: the vote ID must be derived from the process ID, the address
+	// and the k of the voter.
+	RuleVoteID BallotRule = "vote_id"
+	// RuleCipherfields: the cipherfields must be the encryption of the fields
+	// with the k of the voter.
+	RuleCipherfields BallotRule = "cipherfields"
+	// RuleK: the k of the voter must be the one used to compute the vote ID
+	// and the cipherfields.
+	RuleK BallotRule = "k"
+	// RuleInputsHash: the inputs hash must be the hash of the inputs.
+	RuleInputsHash BallotRule = "inputs_hash"
+)
+
+// InvalidBallot is a variant of a valid ballot that breaks one of the rules
+// of the ballot mode.
+type InvalidBallot struct {
+	Rule        BallotRule
+	Description string
+	Fields      []*big.Int
+	Weight      *big.Int
+	// Enforced is false if the circuits do not reject the variant.
+	Enforced bool
+}
+
+// InvalidInputs is a variant of the valid inputs of a ballot proof circuit
+// that breaks one of the rules of the circuit.
+type InvalidInputs struct {
+	Rule        BallotRule
+	Description string
+	Inputs      map[string]any
+}
+
+// InvalidBallots returns the variants of the valid ballot provided that
+// break the rules of the ballot mode, labelled with the rule they break. It
+// includes, when the ballot mode allows it, a duplicated field under
+// uniqueness, a field above the max value or below the min value, a total
+// cost just over the max total cost or under the min total cost, a weight
+// under the total cost and a non-zero padding field.
+func InvalidBallots(fields []*big.Int, mode *BallotMode, weight *big.Int) ([]*InvalidBallot, error) {
+	if weight == nil {
+		weight = big.NewInt(0)
+	}
+	if err := CheckBallot(fields, mode, weight); err != nil {
+		return nil, fmt.Errorf("the ballot provided is not valid: %v", err)
+	}
+	count := int(mode.MaxCount)
+	variants := []*InvalidBallot{}
+	// add appends the variant only if it breaks the expected rule first
+	add := func(rule BallotRule, description string, fields []*big.Int, weight *big.Int) {
+		err := CheckBallot(fields, mode, weight)
+		if ballotErr, ok := err.(*BallotError); ok && ballotErr.Rule == rule {
+			variants = append(variants, &InvalidBallot{
+				Rule:        rule,
+				Description: description,
+				Fields:      fields,
+				Weight:      weight,
+				Enforced:    true,
+			})
+		}
+	}
+	// duplicate every considered field in the next one
+	if mode.ForceUniqueness {
+		for i := 1; i < count; i++ {
+			mutated := copyBigInts(fields)
+			mutated[i] = new(big.Int).Set(mutated[i-1])
+			add(RuleUniqueness, fmt.Sprintf("field %d duplicates field %d", i, i-1), mutated, weight)
+		}
+	}
+	// move every considered field just out of the value bounds
+	for i := 0; i < count; i++ {
+		mutated := copyBigInts(fields)
+		mutated[i] = new(big.Int).SetUint64(mode.MaxValue + 1)
+		add(RuleMaxValue, fmt.Sprintf("field %d above max value", i), mutated, weight)
+		if mode.MinValue > 0 {
+			mutated := copyBigInts(fields)
+			mutated[i] = new(big.Int).SetUint64(mode.MinValue - 1)
+			add(RuleMinValue, fmt.Sprintf("field %d below min value", i), mutated, weight)
+		}
+	}
+	// change a field to get a total cost just over the max bound or under the
+	// min bound
+	if maxTotalCost := mode.maxTotalCost(); maxTotalCost.Sign() > 0 {
+		bound := maxTotalCost
+		if mode.CostFromWeight {
+			bound = weight
+		}
+		if mutated, ok := costMutation(fields, mode, bound, true); ok {
+			add(RuleMaxTotalCost, "total cost over the max total cost", mutated, weight)
+		}
+		// reduce the weight under the total cost
+		if mode.CostFromWeight {
+			cost := TotalCost(fields[:count], mode.CostExp)
+			if cost.Sign() > 0 {
+				add(RuleMaxTotalCost, "weight under the total cost", copyBigInts(fields),
+					new(big.Int).Sub(cost, big.NewInt(1)))
+			}
+		}
+	}
+	if minTotalCost := mode.minTotalCost(); minTotalCost.Sign() > 0 {
+		if mutated, ok := costMutation(fields, mode, minTotalCost, false); ok {
+			add(RuleMinTotalCost, "total cost under the min total cost", mutated, weight)
+		}
+	}
+	// set a non-zero value in the first padding field
+	if count < len(fields) {
+		mutated := copyBigInts(fields)
+		mutated[count] = big.NewInt(1)
+		if mutated[count].Cmp(fields[count]) == 0 {
+			mutated[count] = big.NewInt(2)
+		}
+		variants = append(variants, &InvalidBallot{
+			Rule:        RulePadding,
+			Description: fmt.Sprintf("non-zero padding field %d", count),
+			Fields:      mutated,
+			Weight:      weight,
+			Enforced:    false,
+		})
+	}
+	return variants, nil
+}
+
+// InvalidProofInputs returns the variants of the valid inputs of a ballot
+// proof circuit provided that break the rules of the circuit not related
+// with the ballot mode, labelled with the rule they break: a wrong vote ID,
+// a tampered cipherfield, a different k and, for the hashed inputs variants,
+// a wrong inputs hash. The inputs hash of the other variants is recomputed
+// after the mutation, so they only break the rule they are labelled with.
+func InvalidProofInputs(inputs *BallotProofInputs) ([]*InvalidInputs, error) {
+	if inputs.VoteID == nil || inputs.K == nil || len(inputs.Cipherfields) == 0 {
+		return nil, fmt.Errorf("incomplete inputs")
+	}
+	variants := []*InvalidInputs{}
+	// add appends the mutation of a copy of the inputs with its inputs hash
+	// recomputed
+	add := func(rule BallotRule, description string, mutate func(b *BallotProofInputs)) error {
+		mutated := *inputs
+		mutate(&mutated)
+		if err := mutated.recomputeInputsHash(); err != nil {
+			return err
+		}
+		variants = append(variants, &InvalidInputs{Rule: rule, Description: description, Inputs: mutated.Map()})
+		return nil
+	}
+	// increase the vote ID keeping it in 160 bits
+	if err := add(RuleVoteID, "wrong vote ID", func(b *BallotProofInputs) {
+		b.VoteID = TruncateTo160Bits(new(big.Int).Add(b.VoteID, big.NewInt(1)))
+	}); err != nil {
+		return nil, err
+	}
+	// add the base point to the second point of the first cipherfield, which
+	// results in a valid encryption of a different message
+	first, err := CiphertextFromStrings(inputs.Cipherfields[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid cipherfields input: %v", err)
+	}
+	first.C2 = addPoints(first.C2, babyjub.B8)
+	if err := add(RuleCipherfields, "tampered cipherfield 0", func(b *BallotProofInputs) {
+		b.Cipherfields = append([][][]string{first.Strings()}, b.Cipherfields[1:]...)
+	}); err != nil {
+		return nil, err
+	}
+	// use a different k
+	k, err := RandomK()
+	if err != nil {
+		return nil, err
+	}
+	if err := add(RuleK, "different k", func(b *BallotProofInputs) {
+		b.K = k
+	}); err != nil {
+		return nil, err
+	}
+	// increase the inputs hash if it is an input, keeping it in the field
+	if inputs.InputsHash != nil {
+		mutated := *inputs
+		mutated.InputsHash = new(big.Int).Add(inputs.InputsHash, big.NewInt(1))
+		mutated.InputsHash.Mod(mutated.InputsHash, constants.Q)
+		variants = append(variants, &InvalidInputs{
+			Rule:        RuleInputsHash,
+			Description: "wrong inputs hash",
+			Inputs:      mutated.Map(),
+		})
+	}
+	return variants, nil
+}
+
+// costMutation looks for a change of a considered field that moves the total
+// cost of the ballot just over (or under) the bound provided, keeping the
+// fields in the value bounds and unique if required. If no single change is
+// enough, it uses the highest (or lowest) values for every considered field.
+func costMutation(fields []*big.Int, mode *BallotMode, bound *big.Int, over bool) ([]*big.Int, bool) {
+	count := int(mode.MaxCount)
+	for i := 0; i < count; i++ {
+		rest := new(big.Int).Sub(TotalCost(fields[:count], mode.CostExp), mode.Cost(fields[i].Uint64()))
+		cost := func(v uint64) *big.Int { return new(big.Int).Add(rest, mode.Cost(v)) }
+		n := int(mode.MaxValue - mode.MinValue + 1)
+		var value uint64
+		if over {
+			// the lowest value that exceeds the bound
+			j := sort.Search(n, func(j int) bool { return cost(mode.MinValue+uint64(j)).Cmp(bound) > 0 })
+			if j == n {
+				continue
+			}
+			value = mode.MinValue + uint64(j)
+		} else {
+			// the highest value that is under the bound
+			j := sort.Search(n, func(j int) bool { return cost(mode.MinValue+uint64(j)).Cmp(bound) >= 0 })
+			if j == 0 {
+				continue
+			}
+			value = mode.MinValue + uint64(j-1)
+		}
+		mutated := copyBigInts(fields)
+		mutated[i] = new(big.Int).SetUint64(value)
+		if mode.ForceUniqueness && hasDuplicates(mutated[:count]) {
+			continue
+		}
+		return mutated, true
+	}
+	mutated := copyBigInts(fields)
+	for i := 0; i < count; i++ {
+		value := mode.MinValue
+		if over {
+			value = mode.MaxValue
+		}
+		if mode.ForceUniqueness {
+			if over {
+				value -= uint64(i)
+			} else {
+				value += uint64(i)
+			}
+		}
+		mutated[i] = new(big.Int).SetUint64(value)
+	}
+	cmp := TotalCost(mutated[:count], mode.CostExp).Cmp(bound)
+	if (over && cmp > 0) || (!over && cmp < 0) {
+		return mutated, true
+	}
+	return nil, false
+}
+
+func hasDuplicates(values []*big.Int) bool {
+	seen := map[string]bool{}
+	for _, v := range values {
+		if seen[v.String()] {
+			return true
+		}
+		seen[v.String()] = true
+	}
+	return false
+}
+
+func copyBigInts(values []*big.Int) []*big.Int {
+	copied := make([]*big.Int, len(values))
+	for i, v := range values {
+		copied[i] = new(big.Int).Set(v)
+	}
+	return copied
+}
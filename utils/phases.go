@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ProofPhase identifies a phase of the proof generation.
+type ProofPhase string
+
+const (
+	// ProofPhaseRead is the phase where the inputs and the circuit artifacts
+	// are read and the witness calculator is instanced, or acquired from the
+	// pool of a Prover.
+	ProofPhaseRead ProofPhase = "read"
+	// ProofPhaseWitness is the phase where the witness is calculated.
+	ProofPhaseWitness ProofPhase = "witness"
+	// ProofPhaseProve is the phase where the proof is generated from the
+	// witness.
+	ProofPhaseProve ProofPhase = "prove"
+)
+
+// PhaseError is the error returned when a phase of the proof generation
+// fails or is cancelled. If it was cancelled, it wraps the error of the
+// context, so errors.Is(err, context.Canceled) and
+// errors.Is(err, context.DeadlineExceeded) work as expected.
+type PhaseError struct {
+	Phase ProofPhase
+	Err   error
+}
+
+func (e *PhaseError) Error() string {
+	return fmt.Sprintf("%s phase: %v", e.Phase, e.Err)
+}
+
+// Unwrap returns the error of the phase.
+func (e *PhaseError) Unwrap() error {
+	return e.Err
+}
+
+// ProofTimings contains the duration of every phase of a proof generation.
+// The phases that have not been started have a zero duration.
+type ProofTimings struct {
+	Read    time.Duration
+	Witness time.Duration
+	Prove   time.Duration
+}
+
+// Total returns the duration of every phase together.
+func (t *ProofTimings) Total() time.Duration {
+	return t.Read + t.Witness + t.Prove
+}
+
+// runPhase runs the function provided as the phase provided, recording its
+// duration. It does not start the phase if the context is already done, and
+// it returns as soon as the context is done, leaving the function to finish
+// in the background. The optional abandon function is called to release the
+// resources held for the phase when its result is discarded: if the phase is
+// not started, or if the function succeeds after the phase is cancelled.
+func runPhase(ctx context.Context, phase ProofPhase, timings *ProofTimings, fn func() error, abandon func()) error {
+	if err := ctx.Err(); err != nil {
+		if abandon != nil {
+			abandon()
+		}
+		return &PhaseError{Phase: phase, Err: err}
+	}
+	start := time.Now()
+	done := make(chan error)
+	abandoned := make(chan struct{})
+	go func() {
+		err := fn()
+		select {
+		case done <- err:
+		case <-abandoned:
+			if err == nil && abandon != nil {
+				abandon()
+			}
+		}
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		close(abandoned)
+		err = ctx.Err()
+	}
+	switch phase {
+	case ProofPhaseRead:
+		timings.Read = time.Since(start)
+	case ProofPhaseWitness:
+		timings.Witness = time.Since(start)
+	case ProofPhaseProve:
+		timings.Prove = time.Since(start)
+	}
+	if err != nil {
+		return &PhaseError{Phase: phase, Err: err}
+	}
+	return nil
+}
@@ -1,50 +1,89 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"os"
+	"runtime"
 
-	"github.com/iden3/go-rapidsnark/prover"
 	"github.com/iden3/go-rapidsnark/types"
 	"github.com/iden3/go-rapidsnark/verifier"
-	"github.com/iden3/go-rapidsnark/witness"
+	"github.com/iden3/go-rapidsnark/witness/v2"
+	"github.com/vocdoni/z-ircuits/utils/groth16"
 )
 
-type ProofData struct {
-	A []string   `json:"pi_a"`
-	B [][]string `json:"pi_b"`
-	C []string   `json:"pi_c"`
+// ProofData is a Groth16 proof in the snarkjs JSON format.
+type ProofData = groth16.ProofData
+
+// witnessCalculator calculates the witness of a circuit in the wtns format.
+// It uses wasmer when building with cgo and wazero otherwise.
+type witnessCalculator interface {
+	CalculateWTNSBin(inputs map[string]any, sanityCheck bool) ([]byte, error)
 }
 
 func CompileAndGenerateProof(inputs []byte, wasmFile, zkeyFile string) (string, string, error) {
-	finalInputs, err := witness.ParseInputs(inputs)
-	if err != nil {
-		return "", "", err
-	}
-	// read wasm file
-	bWasm, err := os.ReadFile(wasmFile)
-	if err != nil {
-		return "", "", err
-	}
-	// read zkey file
-	bZkey, err := os.ReadFile(zkeyFile)
-	if err != nil {
-		return "", "", err
-	}
-	// instance witness calculator
-	calc, err := witness.NewCircom2WitnessCalculator(bWasm, true)
-	if err != nil {
-		return "", "", err
+	proof, pubSignals, _, err := CompileAndGenerateProofContext(context.Background(), inputs, wasmFile, zkeyFile)
+	return proof, pubSignals, err
+}
+
+// CompileAndGenerateProofContext is the same as CompileAndGenerateProof but
+// it stops when the context is done, returning a *PhaseError with the phase
+// that was cancelled, and it returns the duration of every phase.
+func CompileAndGenerateProofContext(ctx context.Context, inputs []byte, wasmFile, zkeyFile string,
+) (string, string, *ProofTimings, error) {
+	var (
+		timings     = &ProofTimings{}
+		finalInputs map[string]any
+		bZkey       []byte
+		calc        witnessCalculator
+		w           []byte
+		proof       string
+		pubSignals  string
+	)
+	if err := runPhase(ctx, ProofPhaseRead, timings, func() error {
+		var err error
+		if finalInputs, err = witness.ParseInputs(inputs); err != nil {
+			return err
+		}
+		// read wasm file
+		bWasm, err := os.ReadFile(wasmFile)
+		if err != nil {
+			return err
+		}
+		// read zkey file
+		if bZkey, err = os.ReadFile(zkeyFile); err != nil {
+			return err
+		}
+		// instance witness calculator
+		calc, err = newWitnessCalculator(bWasm)
+		return err
+	}, nil); err != nil {
+		return "", "", timings, err
 	}
 	// calculate witness
-	w, err := calc.CalculateWTNSBin(finalInputs, true)
-	if err != nil {
-		return "", "", err
+	if err := runPhase(ctx, ProofPhaseWitness, timings, func() error {
+		var err error
+		w, err = calc.CalculateWTNSBin(finalInputs, true)
+		return err
+	}, nil); err != nil {
+		return "", "", timings, err
 	}
 	// generate proof
-	return prover.Groth16ProverRaw(bZkey, w)
+	if err := runPhase(ctx, ProofPhaseProve, timings, func() error {
+		var err error
+		proof, pubSignals, err = DefaultProverBackend.Prove(bZkey, w)
+		return err
+	}, nil); err != nil {
+		return "", "", timings, err
+	}
+	return proof, pubSignals, timings, nil
 }
 
+// VerifyProof checks the proof and the public signals provided with the
+// snarkjs verification key provided, using the go-rapidsnark verifier. To
+// verify many proofs of the same circuit, parse the key once with
+// groth16.ParseVerificationKey and use the pure Go verifier instead.
 func VerifyProof(proofData, pubSignals string, vkey []byte) error {
 	data := ProofData{}
 	if err := json.Unmarshal([]byte(proofData), &data); err != nil {
@@ -64,3 +103,180 @@ func VerifyProof(proofData, pubSignals string, vkey []byte) error {
 	}
 	return verifier.VerifyGroth16(proof, vkey)
 }
+
+// Prover generates proofs of a circuit reusing its artifacts: the wasm and
+// the zkey are read once, and the witness calculators are kept in a pool to
+// be reused by the next proofs instead of instantiating the wasm every time.
+// It is safe to use it from many goroutines concurrently.
+type Prover struct {
+	wasm    []byte
+	zkey    []byte
+	backend ProverBackend
+	// slots limits the number of witness calculators in use
+	slots chan struct{}
+	// calculators contains the idle witness calculators of the pool
+	calculators chan witnessCalculator
+}
+
+// NewProver returns a Prover of the circuit with the wasm and the zkey
+// files provided, with a pool of up to poolSize witness calculators. If
+// poolSize is not positive, the number of CPUs is used.
+func NewProver(wasmFile, zkeyFile string, poolSize int) (*Prover, error) {
+	wasm, err := os.ReadFile(wasmFile)
+	if err != nil {
+		return nil, err
+	}
+	zkey, err := os.ReadFile(zkeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return NewProverFromBytes(wasm, zkey, poolSize)
+}
+
+// NewProverFromBytes returns a Prover of the circuit with the wasm and the
+// zkey contents provided. See NewProver.
+func NewProverFromBytes(wasm, zkey []byte, poolSize int) (*Prover, error) {
+	return NewProverWithBackend(wasm, zkey, poolSize, DefaultProverBackend)
+}
+
+// NewProverWithBackend returns a Prover of the circuit with the wasm and
+// the zkey contents provided that generates the proofs with the backend
+// provided. See NewProver.
+func NewProverWithBackend(wasm, zkey []byte, poolSize int, backend ProverBackend) (*Prover, error) {
+	if len(wasm) == 0 {
+		return nil, fmt.Errorf("wasm is empty")
+	}
+	if len(zkey) == 0 {
+		return nil, fmt.Errorf("zkey is empty")
+	}
+	if poolSize <= 0 {
+		poolSize = runtime.NumCPU()
+	}
+	if backend == nil {
+		backend = DefaultProverBackend
+	}
+	// the pure Go backend parses the zkey once instead of on every proof
+	if b, ok := backend.(*GoBackend); ok {
+		var err error
+		if backend, err = b.withZKey(zkey); err != nil {
+			return nil, err
+		}
+	}
+	p := &Prover{
+		wasm:        wasm,
+		zkey:        zkey,
+		backend:     backend,
+		slots:       make(chan struct{}, poolSize),
+		calculators: make(chan witnessCalculator, poolSize),
+	}
+	// instance the first witness calculator to check the wasm
+	calc, err := p.acquire(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	p.release(calc)
+	return p, nil
+}
+
+// Prove calculates the witness of the circuit for the JSON inputs provided
+// and generates its proof, returning the proof and the public signals in
+// the same format as CompileAndGenerateProof. If every witness calculator of
+// the pool is in use, it waits until one is released. It stops when the
+// context is done, returning a *PhaseError with the phase that was
+// cancelled.
+func (p *Prover) Prove(ctx context.Context, inputs []byte) (string, string, error) {
+	proof, pubSignals, _, err := p.ProveWithTimings(ctx, inputs)
+	return proof, pubSignals, err
+}
+
+// ProveWithTimings is the same as Prove but it also returns the duration of
+// every phase of the proof generation.
+func (p *Prover) ProveWithTimings(ctx context.Context, inputs []byte) (string, string, *ProofTimings, error) {
+	var (
+		timings     = &ProofTimings{}
+		finalInputs map[string]any
+		calc        witnessCalculator
+		w           []byte
+		proof       string
+		pubSignals  string
+	)
+	if err := runPhase(ctx, ProofPhaseRead, timings, func() error {
+		var err error
+		if finalInputs, err = witness.ParseInputs(inputs); err != nil {
+			return err
+		}
+		calc, err = p.acquire(ctx)
+		return err
+	}, func() {
+		// the calculator was acquired after the phase was cancelled
+		if calc != nil {
+			p.release(calc)
+		}
+	}); err != nil {
+		return "", "", timings, err
+	}
+	// calculate witness, releasing the calculator even if the phase is
+	// cancelled
+	if err := runPhase(ctx, ProofPhaseWitness, timings, func() error {
+		var err error
+		if w, err = calc.CalculateWTNSBin(finalInputs, true); err != nil {
+			// the calculator could be in an inconsistent state after a
+			// failure, so it is discarded
+			p.discard()
+			return err
+		}
+		return nil
+	}, func() {
+		p.release(calc)
+	}); err != nil {
+		return "", "", timings, err
+	}
+	p.release(calc)
+	// generate proof
+	if err := runPhase(ctx, ProofPhaseProve, timings, func() error {
+		var err error
+		proof, pubSignals, err = p.backend.Prove(p.zkey, w)
+		return err
+	}, nil); err != nil {
+		return "", "", timings, err
+	}
+	return proof, pubSignals, timings, nil
+}
+
+// PoolSize returns the maximum number of witness calculators of the pool.
+func (p *Prover) PoolSize() int {
+	return cap(p.slots)
+}
+
+// acquire waits for a free slot of the pool and returns an idle witness
+// calculator, or a new one if there is none.
+func (p *Prover) acquire(ctx context.Context) (witnessCalculator, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case calc := <-p.calculators:
+		return calc, nil
+	default:
+	}
+	calc, err := newWitnessCalculator(p.wasm)
+	if err != nil {
+		p.discard()
+		return nil, fmt.Errorf("failed to instance witness calculator: %v", err)
+	}
+	return calc, nil
+}
+
+// release returns the witness calculator provided to the pool.
+func (p *Prover) release(calc witnessCalculator) {
+	p.calculators <- calc
+	<-p.slots
+}
+
+// discard frees the slot of an acquired witness calculator without
+// returning it to the pool.
+func (p *Prover) discard() {
+	<-p.slots
+}
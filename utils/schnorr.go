@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	"go.vocdoni.io/dvote/util"
+)
+
+// KeyProof is a non-interactive Schnorr proof of knowledge of the private
+// key of an election public key, bound to a process ID to avoid its reuse
+// in other processes. It is made non-interactive using the Fiat-Shamir
+// heuristic with the Poseidon hash.
+type KeyProof struct {
+	Challenge *big.Int `json:"challenge"`
+	Response  *big.Int `json:"response"`
+}
+
+// keyProofDomain is the first input of the challenges of the key proofs, to
+// separate them from the ones of other proofs that use the same hash.
+var keyProofDomain = new(big.Int).SetBytes([]byte("z-ircuits/key_proof"))
+
+// ProveKeyKnowledge generates a proof of knowledge of the private key
+// provided for the process ID provided.
+func ProveKeyKnowledge(privKey babyjub.PrivateKey, processID []byte) (*KeyProof, error) {
+	sk := privKey.Scalar().BigInt()
+	pk := privKey.Public().Point()
+	w, err := RandomK()
+	if err != nil {
+		return nil, err
+	}
+	// a = [w] * G
+	a := babyjub.NewPoint().Mul(w, babyjub.B8)
+	c, err := keyProofChallenge(processID, pk, a)
+	if err != nil {
+		return nil, err
+	}
+	// s = w + c * sk
+	s := new(big.Int).Mul(c, sk)
+	s.Add(s, w)
+	s.Mod(s, babyjub.SubOrder)
+	return &KeyProof{Challenge: c, Response: s}, nil
+}
+
+// VerifyKeyKnowledge checks the proof of knowledge of the private key of the
+// public key provided for the process ID provided. It also checks that the
+// public key is a valid encryption key for the circuits: a point of the
+// curve subgroup that is not the identity.
+func VerifyKeyKnowledge(publicKey *babyjub.PublicKey, processID []byte, proof *KeyProof) error {
+	if proof == nil || proof.Challenge == nil || proof.Response == nil {
+		return fmt.Errorf("incomplete proof")
+	}
+	if proof.Response.Sign() < 0 || proof.Response.Cmp(babyjub.SubOrder) >= 0 {
+		return fmt.Errorf("response out of range")
+	}
+	pk := publicKey.Point()
+	if !pk.InSubGroup() {
+		return fmt.Errorf("public key not in curve subgroup")
+	}
+	if pk.X.Sign() == 0 && pk.Y.Cmp(big.NewInt(1)) == 0 {
+		return fmt.Errorf("public key is the identity point")
+	}
+	// a = [s] * G - [c] * pk
+	a := addPoints(babyjub.NewPoint().Mul(proof.Response, babyjub.B8),
+		negPoint(babyjub.NewPoint().Mul(proof.Challenge, pk)))
+	c, err := keyProofChallenge(processID, pk, a)
+	if err != nil {
+		return err
+	}
+	if c.Cmp(proof.Challenge) != 0 {
+		return fmt.Errorf("invalid proof")
+	}
+	return nil
+}
+
+// keyProofChallenge computes the Fiat-Shamir challenge of the proof, hashing
+// the domain, the process ID, the base point, the public key and the
+// commitment, and reducing the result to the subgroup order.
+func keyProofChallenge(processID []byte, pk, a *babyjub.Point) (*big.Int, error) {
+	hash, err := MultiPoseidon(
+		keyProofDomain,
+		util.BigToFF(new(big.Int).SetBytes(processID)),
+		babyjub.B8.X, babyjub.B8.Y,
+		pk.X, pk.Y,
+		a.X, a.Y,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute challenge: %v", err)
+	}
+	return hash.Mod(hash, babyjub.SubOrder), nil
+}
@@ -0,0 +1,270 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	"github.com/vocdoni/z-ircuits/utils/groth16"
+)
+
+// BallotProofSignals contains the public signals of the ballot_proof.circom
+// circuit. Circom sorts the public signals of the main component by the
+// declaration order of the template inputs, so the order is: max_count,
+// force_uniqueness, max_value, min_value, max_total_cost, min_total_cost,
+// cost_exp, cost_from_weight, address, weight, process_id, vote_id, pk and
+// cipherfields, 14 + 4 * nFields signals. The pk signals were added to bind
+// the proofs to the election key, so the proofs of the previous version of
+// the circuit, without them, can not be decoded.
+type BallotProofSignals struct {
+	BallotMode    *BallotMode
+	Address       *big.Int
+	Weight        *big.Int
+	ProcessID     *big.Int
+	VoteID        *big.Int
+	EncryptionKey *babyjub.PublicKey
+	// Cipherfields contains the ciphertext of every field, or nil for the
+	// padding cipherfields filled with zeros.
+	Cipherfields []*Ciphertext
+}
+
+// BallotCheckerSignals contains the public signals of the BallotChecker
+// circuit, which are its outputs: the mask of the fields considered.
+type BallotCheckerSignals struct {
+	Mask []bool
+}
+
+// BallotCipherSignals contains the public signals of the ballot cipher test
+// circuit: pk, msg, k, c1 and c2.
+type BallotCipherSignals struct {
+	PublicKey *babyjub.PublicKey
+	Msg       *big.Int
+	K         *big.Int
+	C1        *babyjub.Point
+	C2        *babyjub.Point
+}
+
+// DecodeBallotProofSignals decodes the public signals of a ballot_proof.circom
+// circuit of nFields fields, in the JSON format returned by
+// CompileAndGenerateProof.
+func DecodeBallotProofSignals(pubSignals string, nFields int) (*BallotProofSignals, error) {
+	values, err := groth16.ParseSignals(pubSignals, 14+4*nFields)
+	if err != nil {
+		return nil, err
+	}
+	forceUniqueness, err := signalToBool(values[1], "force_uniqueness")
+	if err != nil {
+		return nil, err
+	}
+	costFromWeight, err := signalToBool(values[7], "cost_from_weight")
+	if err != nil {
+		return nil, err
+	}
+	uint64Signals := map[string]*big.Int{
+		"max_count": values[0],
+		"max_value": values[2],
+		"min_value": values[3],
+		"cost_exp":  values[6],
+	}
+	for name, value := range uint64Signals {
+		if !value.IsUint64() {
+			return nil, fmt.Errorf("invalid %s signal: %s", name, value)
+		}
+	}
+	signals := &BallotProofSignals{
+		BallotMode: &BallotMode{
+			MaxCount:        values[0].Uint64(),
+			ForceUniqueness: forceUniqueness,
+			MaxValue:        values[2].Uint64(),
+			MinValue:        values[3].Uint64(),
+			MaxTotalCost:    values[4],
+			MinTotalCost:    values[5],
+			CostExp:         values[6].Uint64(),
+			CostFromWeight:  costFromWeight,
+		},
+		Address:       values[8],
+		Weight:        values[9],
+		ProcessID:     values[10],
+		VoteID:        values[11],
+		EncryptionKey: &babyjub.PublicKey{X: values[12], Y: values[13]},
+		Cipherfields:  make([]*Ciphertext, nFields),
+	}
+	if !signals.EncryptionKey.Point().InCurve() {
+		return nil, fmt.Errorf("invalid pk signals: point not in curve")
+	}
+	for f := range signals.Cipherfields {
+		coords := values[14+4*f : 18+4*f]
+		if isZeroSignals(coords) {
+			continue
+		}
+		c, err := CiphertextFromBigInts(coords)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cipherfield %d signals: %v", f, err)
+		}
+		signals.Cipherfields[f] = c
+	}
+	return signals, nil
+}
+
+// NewBallotProofSignals returns the public signals of the ballot_proof.circom
+// circuit expected for the ballot of the voter in the process provided.
+func NewBallotProofSignals(process *Process, voter *Voter, ballot *EncryptedBallot) (*BallotProofSignals, error) {
+	if process.EncryptionKey == nil {
+		return nil, fmt.Errorf("missing encryption key")
+	}
+	weight := voter.Weight
+	if weight == nil {
+		weight = big.NewInt(0)
+	}
+	signals := &BallotProofSignals{
+		BallotMode:    process.BallotMode,
+		Address:       voter.Address,
+		Weight:        weight,
+		ProcessID:     process.ID,
+		VoteID:        ballot.VoteID,
+		EncryptionKey: process.EncryptionKey,
+		Cipherfields:  make([]*Ciphertext, len(ballot.Cipherfields)),
+	}
+	for f, cipherfield := range ballot.Cipherfields {
+		if isZeroCipherfield(cipherfield) {
+			continue
+		}
+		c, err := CiphertextFromStrings(cipherfield)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cipherfield %d: %v", f, err)
+		}
+		signals.Cipherfields[f] = c
+	}
+	return signals, nil
+}
+
+// Encode returns the public signals in the circuit order.
+func (s *BallotProofSignals) Encode() []string {
+	values := []*big.Int{}
+	values = append(values, s.BallotMode.BigInts()...)
+	values = append(values, s.Address, s.Weight, s.ProcessID, s.VoteID, s.EncryptionKey.X, s.EncryptionKey.Y)
+	for _, c := range s.Cipherfields {
+		if c == nil {
+			values = append(values, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0))
+			continue
+		}
+		values = append(values, c.C1.X, c.C1.Y, c.C2.X, c.C2.Y)
+	}
+	return BigIntArrayToStringArray(values, len(values))
+}
+
+// JSON returns the public signals in the JSON format returned by
+// CompileAndGenerateProof.
+func (s *BallotProofSignals) JSON() (string, error) {
+	return signalsToJSON(s.Encode())
+}
+
+// DecodeInputsHashSignals decodes the public signals of the hashed inputs
+// variants of the ballot proof circuit, which only include the inputs hash.
+func DecodeInputsHashSignals(pubSignals string) (*big.Int, error) {
+	values, err := groth16.ParseSignals(pubSignals, 1)
+	if err != nil {
+		return nil, err
+	}
+	return values[0], nil
+}
+
+// EncodeInputsHashSignals returns the public signals of the hashed inputs
+// variants of the ballot proof circuit for the inputs hash provided, in the
+// JSON format returned by CompileAndGenerateProof.
+func EncodeInputsHashSignals(inputsHash *big.Int) (string, error) {
+	return signalsToJSON([]string{inputsHash.String()})
+}
+
+// DecodeBallotCheckerSignals decodes the public signals of a BallotChecker
+// circuit of nFields fields.
+func DecodeBallotCheckerSignals(pubSignals string, nFields int) (*BallotCheckerSignals, error) {
+	values, err := groth16.ParseSignals(pubSignals, nFields)
+	if err != nil {
+		return nil, err
+	}
+	signals := &BallotCheckerSignals{Mask: make([]bool, nFields)}
+	for i, value := range values {
+		if signals.Mask[i], err = signalToBool(value, fmt.Sprintf("mask[%d]", i)); err != nil {
+			return nil, err
+		}
+	}
+	return signals, nil
+}
+
+// Encode returns the public signals in the circuit order.
+func (s *BallotCheckerSignals) Encode() []string {
+	values := make([]string, len(s.Mask))
+	for i, m := range s.Mask {
+		values[i] = boolToBigInt(m).String()
+	}
+	return values
+}
+
+// DecodeBallotCipherSignals decodes the public signals of the ballot cipher
+// test circuit.
+func DecodeBallotCipherSignals(pubSignals string) (*BallotCipherSignals, error) {
+	values, err := groth16.ParseSignals(pubSignals, 8)
+	if err != nil {
+		return nil, err
+	}
+	signals := &BallotCipherSignals{
+		PublicKey: &babyjub.PublicKey{X: values[0], Y: values[1]},
+		Msg:       values[2],
+		K:         values[3],
+		C1:        &babyjub.Point{X: values[4], Y: values[5]},
+		C2:        &babyjub.Point{X: values[6], Y: values[7]},
+	}
+	for _, p := range []*babyjub.Point{signals.PublicKey.Point(), signals.C1, signals.C2} {
+		if !p.InCurve() {
+			return nil, fmt.Errorf("invalid signals: point not in curve")
+		}
+	}
+	return signals, nil
+}
+
+// Encode returns the public signals in the circuit order.
+func (s *BallotCipherSignals) Encode() []string {
+	values := []*big.Int{s.PublicKey.X, s.PublicKey.Y, s.Msg, s.K, s.C1.X, s.C1.Y, s.C2.X, s.C2.Y}
+	return BigIntArrayToStringArray(values, len(values))
+}
+
+func signalsToJSON(values []string) (string, error) {
+	b, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func signalToBool(value *big.Int, name string) (bool, error) {
+	switch {
+	case value.Sign() == 0:
+		return false, nil
+	case value.Cmp(big.NewInt(1)) == 0:
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid %s signal: %s is not a boolean", name, value)
+	}
+}
+
+func isZeroSignals(values []*big.Int) bool {
+	for _, v := range values {
+		if v.Sign() != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func isZeroCipherfield(cipherfield [][]string) bool {
+	for _, point := range cipherfield {
+		for _, coord := range point {
+			if coord != "0" {
+				return false
+			}
+		}
+	}
+	return true
+}
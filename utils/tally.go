@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/iden3/go-iden3-crypto/babyjub"
+)
+
+// Tally accumulates encrypted ballots adding their cipherfields field by
+// field. Thanks to the additive homomorphism of the ElGamal encryption used,
+// the resulting ciphertexts encrypt the sum of the votes of every field, so
+// the results can be decrypted without decrypting any individual ballot. A
+// Tally is not safe for concurrent use, every worker should use its own
+// accumulator and merge them at the end.
+type Tally struct {
+	fields []*Ciphertext
+	count  uint64
+}
+
+// tallyJSON is the serialization format of the Tally, which uses the same
+// cipherfields format as the circuits.
+type tallyJSON struct {
+	Count        uint64       `json:"count"`
+	Cipherfields [][][]string `json:"cipherfields"`
+}
+
+// NewTally returns an empty Tally for ballots of n fields.
+func NewTally(n int) *Tally {
+	fields := make([]*Ciphertext, n)
+	for i := range fields {
+		fields[i] = ZeroCiphertext()
+	}
+	return &Tally{fields: fields}
+}
+
+// Fields returns the number of fields of the ballots accumulated.
+func (t *Tally) Fields() int {
+	return len(t.fields)
+}
+
+// Count returns the number of ballots accumulated.
+func (t *Tally) Count() uint64 {
+	return t.count
+}
+
+// Ciphertexts returns the accumulated ciphertexts of every field.
+func (t *Tally) Ciphertexts() []*Ciphertext {
+	return append([]*Ciphertext{}, t.fields...)
+}
+
+// Add accumulates a ballot using the cipherfields in the format returned by
+// CipherBallotFields and used by the circuits.
+func (t *Tally) Add(cipherfields [][][]string) error {
+	if len(cipherfields) != len(t.fields) {
+		return fmt.Errorf("invalid number of cipherfields: expected %d, got %d", len(t.fields), len(cipherfields))
+	}
+	ballot := make([]*Ciphertext, len(cipherfields))
+	for i, cipherfield := range cipherfields {
+		c, err := CiphertextFromStrings(cipherfield)
+		if err != nil {
+			return fmt.Errorf("cipherfield %d: %v", i, err)
+		}
+		ballot[i] = c
+	}
+	t.add(ballot, 1)
+	return nil
+}
+
+// AddPlain accumulates a ballot using the plain cipherfields returned by
+// CipherBallotFields, four coordinates per field.
+func (t *Tally) AddPlain(plainCipherfields []*big.Int) error {
+	if len(plainCipherfields) != 4*len(t.fields) {
+		return fmt.Errorf("invalid number of cipherfields coordinates: expected %d, got %d",
+			4*len(t.fields), len(plainCipherfields))
+	}
+	ballot := make([]*Ciphertext, len(t.fields))
+	for i := range ballot {
+		c, err := CiphertextFromBigInts(plainCipherfields[4*i : 4*i+4])
+		if err != nil {
+			return fmt.Errorf("cipherfield %d: %v", i, err)
+		}
+		ballot[i] = c
+	}
+	t.add(ballot, 1)
+	return nil
+}
+
+// Merge accumulates the partial tally provided into the current one.
+func (t *Tally) Merge(o *Tally) error {
+	if len(o.fields) != len(t.fields) {
+		return fmt.Errorf("invalid number of fields: expected %d, got %d", len(t.fields), len(o.fields))
+	}
+	t.add(o.fields, o.count)
+	return nil
+}
+
+// Decrypt decrypts the accumulated result of every field, which must be
+// lower or equal than max.
+func (t *Tally) Decrypt(privKey babyjub.PrivateKey, max uint64) ([]*big.Int, error) {
+	results := make([]*big.Int, len(t.fields))
+	for i, c := range t.fields {
+		result, err := Decrypt(privKey, c.C1, c.C2, max)
+		if err != nil {
+			return nil, fmt.Errorf("field %d: %v", i, err)
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+// MarshalJSON encodes the tally using the circuits cipherfields format.
+func (t *Tally) MarshalJSON() ([]byte, error) {
+	cipherfields := make([][][]string, len(t.fields))
+	for i, c := range t.fields {
+		cipherfields[i] = c.Strings()
+	}
+	return json.Marshal(tallyJSON{Count: t.count, Cipherfields: cipherfields})
+}
+
+// UnmarshalJSON decodes a tally encoded with MarshalJSON.
+func (t *Tally) UnmarshalJSON(data []byte) error {
+	decoded := tallyJSON{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	fields := make([]*Ciphertext, len(decoded.Cipherfields))
+	for i, cipherfield := range decoded.Cipherfields {
+		c, err := CiphertextFromStrings(cipherfield)
+		if err != nil {
+			return fmt.Errorf("cipherfield %d: %v", i, err)
+		}
+		fields[i] = c
+	}
+	t.fields = fields
+	t.count = decoded.Count
+	return nil
+}
+
+func (t *Tally) add(ballot []*Ciphertext, count uint64) {
+	for i, c := range ballot {
+		t.fields[i] = t.fields[i].Add(c)
+	}
+	t.count += count
+}
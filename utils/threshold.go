@@ -0,0 +1,292 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/iden3/go-iden3-crypto/babyjub"
+)
+
+// Trustee is a participant of a t-of-n distributed key generation based on
+// Feldman's verifiable secret sharing over BabyJubJub. Every trustee deals a
+// random secret polynomial of degree t-1, sends the evaluation of it to
+// every other trustee and publishes the commitments to its coefficients. The
+// election secret key is the sum of the secrets of every trustee, and it is
+// never reconstructed: any t trustees can decrypt a ciphertext combining
+// their partial decryptions.
+type Trustee struct {
+	Index     int
+	Threshold int
+	Trustees  int
+
+	coefficients []*big.Int
+	commitments  []*babyjub.Point
+	shares       map[int]*big.Int
+	secretShare  *big.Int
+}
+
+// PartialDecryption is the partial decryption of a ciphertext computed by a
+// trustee with its secret share: [share] * c1. It includes a Chaum-Pedersen
+// proof of its correctness against the public share of the trustee.
+type PartialDecryption struct {
+	Index int
+	Point *babyjub.Point
+	Proof *DLEQProof
+}
+
+// NewTrustee creates the trustee with the index provided (from 1 to n) for a
+// t-of-n distributed key generation, generating its secret polynomial.
+func NewTrustee(index, threshold, n int) (*Trustee, error) {
+	if threshold < 1 || threshold > n {
+		return nil, fmt.Errorf("invalid threshold %d for %d trustees", threshold, n)
+	}
+	if index < 1 || index > n {
+		return nil, fmt.Errorf("invalid trustee index %d for %d trustees", index, n)
+	}
+	coefficients := make([]*big.Int, threshold)
+	commitments := make([]*babyjub.Point, threshold)
+	for i := range coefficients {
+		coefficient, err := RandomK()
+		if err != nil {
+			return nil, err
+		}
+		coefficients[i] = coefficient
+		commitments[i] = babyjub.NewPoint().Mul(coefficient, babyjub.B8)
+	}
+	return &Trustee{
+		Index:        index,
+		Threshold:    threshold,
+		Trustees:     n,
+		coefficients: coefficients,
+		commitments:  commitments,
+		shares:       map[int]*big.Int{},
+	}, nil
+}
+
+// Commitments returns the commitments to the coefficients of the secret
+// polynomial of the trustee, which must be published to every other trustee.
+// The first one is the contribution of the trustee to the public key.
+func (t *Trustee) Commitments() []*babyjub.Point {
+	return append([]*babyjub.Point{}, t.commitments...)
+}
+
+// Share returns the evaluation of the secret polynomial of the trustee for
+// the trustee with the index provided, which must be sent privately to it.
+func (t *Trustee) Share(index int) (*big.Int, error) {
+	if index < 1 || index > t.Trustees {
+		return nil, fmt.Errorf("invalid trustee index %d", index)
+	}
+	return evalPolynomial(t.coefficients, big.NewInt(int64(index))), nil
+}
+
+// ReceiveShare verifies the share received from the trustee with the index
+// provided against its published commitments and stores it.
+func (t *Trustee) ReceiveShare(from int, share *big.Int, commitments []*babyjub.Point) error {
+	if from < 1 || from > t.Trustees {
+		return fmt.Errorf("invalid trustee index %d", from)
+	}
+	if len(commitments) != t.Threshold {
+		return fmt.Errorf("invalid number of commitments from trustee %d: expected %d, got %d",
+			from, t.Threshold, len(commitments))
+	}
+	// [share] * G must be equal to sum([index^k] * commitments[k])
+	expected := evalCommitments(commitments, big.NewInt(int64(t.Index)))
+	got := babyjub.NewPoint().Mul(share, babyjub.B8)
+	if expected.X.Cmp(got.X) != 0 || expected.Y.Cmp(got.Y) != 0 {
+		return fmt.Errorf("invalid share from trustee %d", from)
+	}
+	t.shares[from] = new(big.Int).Set(share)
+	return nil
+}
+
+// Finalize computes the secret share of the trustee once it has received
+// the shares of every trustee, including its own.
+func (t *Trustee) Finalize() error {
+	if len(t.shares) != t.Trustees {
+		return fmt.Errorf("missing shares: expected %d, got %d", t.Trustees, len(t.shares))
+	}
+	secretShare := big.NewInt(0)
+	for _, share := range t.shares {
+		secretShare.Add(secretShare, share)
+	}
+	t.secretShare = secretShare.Mod(secretShare, babyjub.SubOrder)
+	return nil
+}
+
+// PartialDecrypt returns the partial decryption of the ciphertext with the
+// first point c1 using the secret share of the trustee, including the proof
+// of its correctness.
+func (t *Trustee) PartialDecrypt(c1 *babyjub.Point) (*PartialDecryption, error) {
+	if t.secretShare == nil {
+		return nil, fmt.Errorf("trustee %d is not finalized", t.Index)
+	}
+	point := babyjub.NewPoint().Mul(t.secretShare, c1)
+	publicShare := babyjub.NewPoint().Mul(t.secretShare, babyjub.B8)
+	proof, err := ProveDLEQ(t.secretShare, babyjub.B8, publicShare, c1, point)
+	if err != nil {
+		return nil, err
+	}
+	return &PartialDecryption{Index: t.Index, Point: point, Proof: proof}, nil
+}
+
+// VerifyPartialDecryption checks the proof of the partial decryption of the
+// ciphertext with the first point c1 against the public share of the trustee,
+// computed from the commitments published by every trustee.
+func VerifyPartialDecryption(commitments [][]*babyjub.Point, c1 *babyjub.Point, partial *PartialDecryption) error {
+	if partial == nil || partial.Point == nil {
+		return fmt.Errorf("incomplete partial decryption")
+	}
+	publicShare := PublicShare(commitments, partial.Index)
+	if err := VerifyDLEQ(partial.Proof, babyjub.B8, publicShare, c1, partial.Point); err != nil {
+		return fmt.Errorf("partial decryption of trustee %d: %v", partial.Index, err)
+	}
+	return nil
+}
+
+// JointPublicKey returns the election public key from the commitments
+// published by every trustee, which is the sum of their first commitments.
+func JointPublicKey(commitments [][]*babyjub.Point) (*babyjub.PublicKey, error) {
+	if len(commitments) == 0 {
+		return nil, fmt.Errorf("no commitments provided")
+	}
+	pk := babyjub.NewPoint()
+	for i, c := range commitments {
+		if len(c) == 0 {
+			return nil, fmt.Errorf("no commitments provided by trustee %d", i+1)
+		}
+		pk = addPoints(pk, c[0])
+	}
+	return (*babyjub.PublicKey)(pk), nil
+}
+
+// PublicShare returns the public share of the trustee with the index
+// provided, [share] * G, from the commitments published by every trustee.
+func PublicShare(commitments [][]*babyjub.Point, index int) *babyjub.Point {
+	publicShare := babyjub.NewPoint()
+	for _, c := range commitments {
+		publicShare = addPoints(publicShare, evalCommitments(c, big.NewInt(int64(index))))
+	}
+	return publicShare
+}
+
+// CombinePartialDecryptions combines the partial decryptions of the
+// ciphertext (c1, c2) using Lagrange interpolation, and returns the point that
+// encodes the message, [message] * G. The threshold is the number of
+// commitments published by every trustee. The partial decryptions that are
+// incomplete, duplicated or that can not be verified against the commitments
+// are skipped, and it fails if less than threshold valid ones remain.
+func CombinePartialDecryptions(commitments [][]*babyjub.Point, c1, c2 *babyjub.Point,
+	partials []*PartialDecryption,
+) (*babyjub.Point, error) {
+	threshold, err := checkCommitments(commitments)
+	if err != nil {
+		return nil, err
+	}
+	valid := []*PartialDecryption{}
+	indexes := []int{}
+	seen := map[int]bool{}
+	for _, partial := range partials {
+		if len(valid) == threshold {
+			break
+		}
+		if partial == nil || partial.Index < 1 || partial.Index > len(commitments) || seen[partial.Index] {
+			continue
+		}
+		if err := VerifyPartialDecryption(commitments, c1, partial); err != nil {
+			continue
+		}
+		seen[partial.Index] = true
+		valid = append(valid, partial)
+		indexes = append(indexes, partial.Index)
+	}
+	if len(valid) < threshold {
+		return nil, fmt.Errorf("not enough valid partial decryptions: expected %d, got %d", threshold, len(valid))
+	}
+	// s = sum([lambda_i] * partial_i)
+	s := babyjub.NewPoint()
+	for _, partial := range valid {
+		lambda := lagrangeCoefficient(partial.Index, indexes)
+		s = addPoints(s, babyjub.NewPoint().Mul(lambda, partial.Point))
+	}
+	// m = c2 - s
+	return addPoints(c2, negPoint(s)), nil
+}
+
+// ThresholdDecrypt combines the partial decryptions of the ciphertext (c1,
+// c2) and recovers the message, which must be lower or equal than max.
+func ThresholdDecrypt(commitments [][]*babyjub.Point, c1, c2 *babyjub.Point,
+	partials []*PartialDecryption, max uint64,
+) (*big.Int, error) {
+	m, err := CombinePartialDecryptions(commitments, c1, c2, partials)
+	if err != nil {
+		return nil, err
+	}
+	return DiscreteLog(m, max)
+}
+
+// checkCommitments checks that every trustee published the same number of
+// commitments, all of them in the curve subgroup, and returns that number,
+// which is the threshold.
+func checkCommitments(commitments [][]*babyjub.Point) (int, error) {
+	if len(commitments) == 0 {
+		return 0, fmt.Errorf("no commitments provided")
+	}
+	threshold := len(commitments[0])
+	if threshold == 0 || threshold > len(commitments) {
+		return 0, fmt.Errorf("invalid threshold %d for %d trustees", threshold, len(commitments))
+	}
+	for i, c := range commitments {
+		if len(c) != threshold {
+			return 0, fmt.Errorf("invalid number of commitments from trustee %d: expected %d, got %d",
+				i+1, threshold, len(c))
+		}
+		for _, point := range c {
+			if point == nil || !point.InSubGroup() {
+				return 0, fmt.Errorf("commitment of trustee %d not in curve subgroup", i+1)
+			}
+		}
+	}
+	return threshold, nil
+}
+
+// evalPolynomial evaluates the polynomial with the coefficients provided in
+// x, modulo the subgroup order.
+func evalPolynomial(coefficients []*big.Int, x *big.Int) *big.Int {
+	result := big.NewInt(0)
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		result.Mul(result, x)
+		result.Add(result, coefficients[i])
+		result.Mod(result, babyjub.SubOrder)
+	}
+	return result
+}
+
+// evalCommitments evaluates the polynomial in the exponent defined by the
+// commitments provided in x: sum([x^k] * commitments[k]).
+func evalCommitments(commitments []*babyjub.Point, x *big.Int) *babyjub.Point {
+	result := babyjub.NewPoint()
+	xk := big.NewInt(1)
+	for _, commitment := range commitments {
+		result = addPoints(result, babyjub.NewPoint().Mul(xk, commitment))
+		xk = new(big.Int).Mul(xk, x)
+		xk.Mod(xk, babyjub.SubOrder)
+	}
+	return result
+}
+
+// lagrangeCoefficient returns the Lagrange coefficient of the index i to
+// interpolate the value in zero using the indexes provided:
+// prod(j / (j - i)) for every j != i.
+func lagrangeCoefficient(i int, indexes []int) *big.Int {
+	num, den := big.NewInt(1), big.NewInt(1)
+	for _, j := range indexes {
+		if j == i {
+			continue
+		}
+		num.Mul(num, big.NewInt(int64(j)))
+		den.Mul(den, big.NewInt(int64(j-i)))
+	}
+	den.Mod(den, babyjub.SubOrder)
+	lambda := num.Mul(num, den.ModInverse(den, babyjub.SubOrder))
+	return lambda.Mod(lambda, babyjub.SubOrder)
+}
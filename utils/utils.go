@@ -80,6 +80,35 @@ func MultiPoseidon(inputs ...*big.Int) (*big.Int, error) {
 	return poseidon.Hash(hashes)
 }
 
+// MultiPoseidonTree hashes any number of inputs using a tree of Poseidon
+// hashes. The inputs are split in chunks of up to 16 elements and every
+// chunk is hashed, then the resulting hashes are split and hashed again in
+// the same way until a single hash remains. It uses the same algorithm as the
+// MultiPoseidonTree circom template, and the result is the same as the one of
+// MultiPoseidon for up to 256 inputs.
+func MultiPoseidonTree(inputs ...*big.Int) (*big.Int, error) {
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no inputs provided")
+	}
+	level := inputs
+	for {
+		// hash every chunk of the current level
+		hashes := make([]*big.Int, 0, (len(level)+15)/16)
+		for i := 0; i < len(level); i += 16 {
+			hash, err := poseidon.Hash(level[i:min(i+16, len(level))])
+			if err != nil {
+				return nil, err
+			}
+			hashes = append(hashes, hash)
+		}
+		// the root of the tree is the only hash of the last level
+		if len(hashes) == 1 {
+			return hashes[0], nil
+		}
+		level = hashes
+	}
+}
+
 func VoteID(bigPID, bigAddr, k *big.Int) (*big.Int, error) {
 	hash, err := mimc7.Hash([]*big.Int{
 		util.BigToFF(bigPID),
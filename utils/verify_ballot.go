@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// BallotProof contains a proof of a ballot proof circuit variant and the
+// public signals it was generated with, in the format returned by
+// CompileAndGenerateProof.
+type BallotProof struct {
+	Variant    BallotProofVariant
+	Proof      string
+	PubSignals string
+}
+
+// SignalMismatchError is returned by VerifyBallot when a public signal of
+// the proof does not match the one expected for the process, the voter and
+// the encrypted ballot.
+type SignalMismatchError struct {
+	Index    int
+	Name     string
+	Expected string
+	Got      string
+}
+
+func (e *SignalMismatchError) Error() string {
+	return fmt.Sprintf("public signal %d (%s) mismatch: expected %s, got %s", e.Index, e.Name, e.Expected, e.Got)
+}
+
+// BallotProofSignalNames returns the names of the public signals of the
+// ballot_proof.circom circuit of nFields fields, in the circuit order.
+func BallotProofSignalNames(nFields int) []string {
+	names := append(ballotModeKeys(), "address", "weight", "process_id", "vote_id", "pk[0]", "pk[1]")
+	for f := 0; f < nFields; f++ {
+		for i := 0; i < 2; i++ {
+			for j := 0; j < 2; j++ {
+				names = append(names, fmt.Sprintf("cipherfields[%d][%d][%d]", f, i, j))
+			}
+		}
+	}
+	return names
+}
+
+// ExpectedPublicSignals returns the public signals that a proof of the
+// ballot proof circuit variant provided must have for the ballot of the
+// voter in the process provided: every public input for the
+// BallotProofPlain variant, or the inputs hash for the hashed variants.
+func ExpectedPublicSignals(variant BallotProofVariant, process *Process, voter *Voter,
+	ballot *EncryptedBallot,
+) ([]string, error) {
+	hasher, err := HasherForVariant(variant)
+	if err != nil {
+		return nil, err
+	}
+	if process.ID == nil || process.BallotMode == nil || process.EncryptionKey == nil {
+		return nil, fmt.Errorf("incomplete process")
+	}
+	if voter.Address == nil {
+		return nil, fmt.Errorf("missing voter address")
+	}
+	if ballot.VoteID == nil {
+		return nil, fmt.Errorf("missing vote ID")
+	}
+	// parsing the signals also checks the format of the cipherfields
+	signals, err := NewBallotProofSignals(process, voter, ballot)
+	if err != nil {
+		return nil, err
+	}
+	if hasher == nil {
+		return signals.Encode(), nil
+	}
+	inputsHash, err := InputsHash(hasher, process, voter, ballot)
+	if err != nil {
+		return nil, err
+	}
+	return []string{inputsHash.String()}, nil
+}
+
+// VerifyBallot checks that the proof provided proves the ballot of the voter
+// in the process provided. It recomputes the public signals expected from
+// the process parameters, the encryption key, the voter address and weight
+// and the encrypted ballot, compares them with the public signals of the
+// proof and verifies the proof with the verification key provided. If a
+// public signal does not match, it returns a *SignalMismatchError.
+func VerifyBallot(vkey []byte, process *Process, voter *Voter, ballot *EncryptedBallot, proof *BallotProof) error {
+	expected, err := ExpectedPublicSignals(proof.Variant, process, voter, ballot)
+	if err != nil {
+		return err
+	}
+	got := []string{}
+	if err := json.Unmarshal([]byte(proof.PubSignals), &got); err != nil {
+		return fmt.Errorf("invalid public signals: %v", err)
+	}
+	if len(got) != len(expected) {
+		return fmt.Errorf("invalid number of public signals: expected %d, got %d", len(expected), len(got))
+	}
+	names := []string{"inputs_hash"}
+	if proof.Variant == BallotProofPlain {
+		names = BallotProofSignalNames(len(ballot.Cipherfields))
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			return &SignalMismatchError{Index: i, Name: names[i], Expected: expected[i], Got: got[i]}
+		}
+	}
+	if err := VerifyProof(proof.Proof, proof.PubSignals, vkey); err != nil {
+		return fmt.Errorf("invalid proof: %v", err)
+	}
+	return nil
+}
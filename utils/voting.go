@@ -0,0 +1,182 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// VotingSystem describes a common voting system in terms of the ballot mode
+// parameters of the circuits. Every voting system uses one ballot field per
+// option, so the encrypted ballots can be tallied homomorphically and the
+// decrypted tally contains the result of every option.
+type VotingSystem struct {
+	Name    string
+	Options int
+	Mode    *BallotMode
+}
+
+// SingleChoice returns the voting system where the voter must choose exactly
+// one of the options.
+func SingleChoice(options int) (*VotingSystem, error) {
+	return newVotingSystem("single-choice", options, &BallotMode{
+		MaxValue:     1,
+		MaxTotalCost: big.NewInt(1),
+		MinTotalCost: big.NewInt(1),
+		CostExp:      1,
+	})
+}
+
+// MultipleChoice returns the voting system where the voter must choose
+// between minChoices and maxChoices different options.
+func MultipleChoice(options, minChoices, maxChoices int) (*VotingSystem, error) {
+	if minChoices < 0 || maxChoices < 1 || minChoices > maxChoices || maxChoices > options {
+		return nil, fmt.Errorf("invalid choices range [%d, %d] for %d options", minChoices, maxChoices, options)
+	}
+	return newVotingSystem("multiple-choice", options, &BallotMode{
+		MaxValue:     1,
+		MaxTotalCost: big.NewInt(int64(maxChoices)),
+		MinTotalCost: big.NewInt(int64(minChoices)),
+		CostExp:      1,
+	})
+}
+
+// Approval returns the voting system where the voter can approve up to
+// maxApprovals options, including none of them.
+func Approval(options, maxApprovals int) (*VotingSystem, error) {
+	system, err := MultipleChoice(options, 0, maxApprovals)
+	if err != nil {
+		return nil, err
+	}
+	system.Name = "approval"
+	return system, nil
+}
+
+// Rating returns the voting system where the voter must rate every option
+// with a value between minRating and maxRating.
+func Rating(options int, minRating, maxRating uint64) (*VotingSystem, error) {
+	return newVotingSystem("rating", options, &BallotMode{
+		MaxValue: maxRating,
+		MinValue: minRating,
+		CostExp:  1,
+	})
+}
+
+// Quadratic returns the voting system where the voter distributes votes
+// between the options, paying the square of the votes of every option from
+// the credits provided.
+func Quadratic(options int, credits uint64) (*VotingSystem, error) {
+	if credits == 0 {
+		return nil, fmt.Errorf("credits must be greater than zero")
+	}
+	// the max votes of an option is the integer square root of the credits
+	maxVotes := new(big.Int).Sqrt(new(big.Int).SetUint64(credits)).Uint64()
+	return newVotingSystem("quadratic", options, &BallotMode{
+		MaxValue:     maxVotes,
+		MaxTotalCost: new(big.Int).SetUint64(credits),
+		CostExp:      2,
+	})
+}
+
+// Cumulative returns the voting system where the voter distributes up to
+// the points provided between the options.
+func Cumulative(options int, points uint64) (*VotingSystem, error) {
+	if points == 0 {
+		return nil, fmt.Errorf("points must be greater than zero")
+	}
+	return newVotingSystem("cumulative", options, &BallotMode{
+		MaxValue:     points,
+		MaxTotalCost: new(big.Int).SetUint64(points),
+		CostExp:      1,
+	})
+}
+
+func newVotingSystem(name string, options int, mode *BallotMode) (*VotingSystem, error) {
+	if options <= 0 {
+		return nil, fmt.Errorf("invalid number of options: %d", options)
+	}
+	mode.MaxCount = uint64(options)
+	if err := mode.Validate(options); err != nil {
+		return nil, fmt.Errorf("invalid %s voting system: %v", name, err)
+	}
+	return &VotingSystem{Name: name, Options: options, Mode: mode}, nil
+}
+
+// Ballot encodes the votes provided, one value per option, as the fields
+// of a ballot for a circuit of nFields fields. It returns a *BallotError if
+// the votes are not valid for the voting system.
+func (v *VotingSystem) Ballot(votes []uint64, nFields int) ([]*big.Int, error) {
+	if len(votes) != v.Options {
+		return nil, fmt.Errorf("invalid number of votes: expected %d, got %d", v.Options, len(votes))
+	}
+	if nFields < v.Options {
+		return nil, fmt.Errorf("%d options do not fit in %d fields", v.Options, nFields)
+	}
+	fields := make([]*big.Int, len(votes))
+	for i, vote := range votes {
+		fields[i] = new(big.Int).SetUint64(vote)
+	}
+	fields = BigIntArrayToN(fields, nFields)
+	if err := CheckBallot(fields, v.Mode, nil); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// Choose encodes the selection of the options provided, by their index, as
+// the fields of a ballot for a circuit of nFields fields. It is intended for
+// the choice voting systems, where the value of every field is 0 or 1.
+func (v *VotingSystem) Choose(nFields int, options ...int) ([]*big.Int, error) {
+	votes := make([]uint64, v.Options)
+	for _, option := range options {
+		if option < 0 || option >= v.Options {
+			return nil, fmt.Errorf("invalid option %d", option)
+		}
+		if votes[option] != 0 {
+			return nil, fmt.Errorf("option %d selected twice", option)
+		}
+		votes[option] = 1
+	}
+	return v.Ballot(votes, nFields)
+}
+
+// Results decodes the decrypted tally of the fields of the ballots of the
+// voting system, returning the result of every option. It also checks that
+// the tally is consistent with the number of ballots provided: the padding
+// fields must be zero, every option can not exceed its max value per ballot
+// and, for the linear cost systems, the sum of the results must be in the
+// cost bounds per ballot.
+func (v *VotingSystem) Results(tally []*big.Int, ballots uint64) ([]*big.Int, error) {
+	if len(tally) < v.Options {
+		return nil, fmt.Errorf("invalid tally size: expected at least %d, got %d", v.Options, len(tally))
+	}
+	for i, padding := range tally[v.Options:] {
+		if padding.Sign() != 0 {
+			return nil, fmt.Errorf("padding field %d is not zero", v.Options+i)
+		}
+	}
+	bigBallots := new(big.Int).SetUint64(ballots)
+	maxOption := new(big.Int).Mul(bigBallots, new(big.Int).SetUint64(v.Mode.MaxValue))
+	minOption := new(big.Int).Mul(bigBallots, new(big.Int).SetUint64(v.Mode.MinValue))
+	results := make([]*big.Int, v.Options)
+	total := big.NewInt(0)
+	for i := range results {
+		if tally[i].Cmp(maxOption) > 0 || tally[i].Cmp(minOption) < 0 {
+			return nil, fmt.Errorf("option %d result %s out of range [%s, %s]", i, tally[i], minOption, maxOption)
+		}
+		results[i] = new(big.Int).Set(tally[i])
+		total.Add(total, tally[i])
+	}
+	if v.Mode.CostExp == 1 {
+		minTotal := new(big.Int).Mul(bigBallots, v.Mode.minTotalCost())
+		if total.Cmp(minTotal) < 0 {
+			return nil, fmt.Errorf("total result %s is lower than %s", total, minTotal)
+		}
+		if maxTotalCost := v.Mode.maxTotalCost(); maxTotalCost.Sign() > 0 {
+			maxTotal := new(big.Int).Mul(bigBallots, maxTotalCost)
+			if total.Cmp(maxTotal) > 0 {
+				return nil, fmt.Errorf("total result %s exceeds %s", total, maxTotal)
+			}
+		}
+	}
+	return results, nil
+}
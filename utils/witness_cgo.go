@@ -0,0 +1,15 @@
+//go:build cgo
+
+package utils
+
+import "github.com/iden3/go-rapidsnark/witness"
+
+// newWitnessCalculator instances a witness calculator of the circuit wasm
+// provided with wasmer, which requires cgo.
+func newWitnessCalculator(wasm []byte) (witnessCalculator, error) {
+	calc, err := witness.NewCircom2WitnessCalculator(wasm, true)
+	if err != nil {
+		return nil, err
+	}
+	return calc, nil
+}
@@ -0,0 +1,14 @@
+//go:build !cgo
+
+package utils
+
+import (
+	"github.com/iden3/go-rapidsnark/witness/v2"
+	"github.com/iden3/go-rapidsnark/witness/wazero"
+)
+
+// newWitnessCalculator instances a witness calculator of the circuit wasm
+// provided with wazero, a pure Go wasm runtime.
+func newWitnessCalculator(wasm []byte) (witnessCalculator, error) {
+	return witness.NewCalculator(wasm, witness.WithWasmEngine(wazero.NewCircom2WZWitnessCalculator))
+}